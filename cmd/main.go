@@ -4,15 +4,46 @@
 // for monitoring, health checks for Kubernetes probes, and a stress test
 // endpoint for demonstrating Horizontal Pod Autoscaler (HPA) behavior.
 //
-// Configuration:
-//   - PORT: HTTP server port (default: 8080)
-//   - LOG_LEVEL: Logging verbosity - debug, info, warn, error (default: info)
+// Configuration is loaded by pkg/config from defaults, an optional YAML/JSON
+// file named by CONFIG_FILE, an optional .env file and environment variables.
+// See the config package for every setting and its environment variable.
+// Changes to the config file and the fault rules file are applied without a
+// restart, except for server, metrics, tracing and custom metrics settings
+// and log settings other than the level.
+//
+// Every request runs in an OpenTelemetry span that continues the caller's
+// W3C traceparent. Spans are exported as configured by the tracing section
+// (OTLP over HTTP or gRPC, or stdout). Every request also gets a correlation
+// ID from its X-Request-ID header, or a generated one, and both IDs are added
+// to request logs and JSON responses.
 //
 // Endpoints:
 //   - GET /         : Main application endpoint with welcome message
-//   - GET /health   : Health check endpoint for Kubernetes probes
+//   - GET /version  : Build version, commit, build date and Go version
+//   - GET /livez    : Liveness probe endpoint
+//   - GET /readyz   : Readiness probe endpoint, fails while draining or saturated
+//   - GET /startupz : Startup probe endpoint, fails until initialization completes
+//   - GET /health   : Legacy alias for /livez
 //   - GET /stress   : CPU stress test endpoint for HPA demonstration
+//   - GET /stress/memory       : Memory stress test endpoint for memory-based HPA and OOM demos
+//   - POST /stress/jobs        : Start a background stress job
+//   - GET /stress/jobs         : List stress jobs
+//   - GET /stress/jobs/{id}    : Get stress job status and progress
+//   - DELETE /stress/jobs/{id} : Cancel a running stress job
+//   - GET /fault    : Latency and error injection endpoint for SLO/alerting demos
 //   - GET /metrics  : Prometheus metrics endpoint
+//   - GET /admin/faults            : List chaos fault rules
+//   - POST /admin/faults           : Create a chaos fault rule
+//   - DELETE /admin/faults         : Remove all chaos fault rules
+//   - DELETE /admin/faults/{id}    : Remove a chaos fault rule
+//   - GET /admin/config            : Effective configuration with secrets redacted
+//   - GET /admin/loglevel          : Effective log levels
+//   - PUT /admin/loglevel          : Override log levels, optionally per component and with a TTL
+//   - DELETE /admin/loglevel       : Revert to the configured log level
+//
+// When custom_metrics.enabled is set, a Kubernetes custom metrics API
+// (custom.metrics.k8s.io) serving the scaling signals listens on HTTPS at
+// custom_metrics.port; see internal/custommetrics.
 //
 // Example:
 //
@@ -20,102 +51,613 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
 
+	"github.com/moabdelazem/go-gitops-app/internal/custommetrics"
+	"github.com/moabdelazem/go-gitops-app/internal/fault"
 	"github.com/moabdelazem/go-gitops-app/internal/handlers"
 	"github.com/moabdelazem/go-gitops-app/internal/middleware"
+	"github.com/moabdelazem/go-gitops-app/pkg/config"
 	"github.com/moabdelazem/go-gitops-app/pkg/logger"
 	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/tracing"
+	"github.com/moabdelazem/go-gitops-app/pkg/version"
 )
 
 func main() {
-	// Load environment variables from .env file if present.
-	// This is a no-op in production where environment variables are set directly.
-	// Errors are ignored as the .env file is optional.
-	_ = godotenv.Load()
+	// Load and validate configuration from all sources, failing fast on
+	// invalid values before anything else starts
+	cfg, err := config.Load()
+	if err != nil {
+		// Initialize the logger with defaults so the failure is still logged
+		logger.Init("")
+		logger.Fatal().
+			Err(err).
+			Msg("Failed to load configuration")
+	}
+	config.Set(cfg)
+
+	// Initialize the structured logger to enable logging throughout startup
+	if err := logger.Configure(logOptions(cfg.Log)); err != nil {
+		logger.Init(cfg.Log.Level)
+		logger.Fatal().
+			Err(err).
+			Msg("Failed to configure logging")
+	}
 
-	// Initialize the structured logger first to enable logging throughout startup
-	logger.Init()
+	// Create the Prometheus metrics registry and the handlers recording
+	// into it
+	registry, err := metrics.NewRegistry(metricsOptions(cfg.Metrics))
+	if err != nil {
+		logger.Fatal().
+			Err(err).
+			Msg("Failed to register metrics")
+	}
+	app := handlers.New(registry)
 
-	// Register Prometheus metrics collectors
-	metrics.Register()
+	// Install the OpenTelemetry tracer provider and propagators
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingOptions(cfg.Tracing))
+	if err != nil {
+		logger.Fatal().
+			Err(err).
+			Msg("Failed to set up tracing")
+	}
 
-	// Create and configure the Gorilla Mux router
-	router := setupRouter()
+	// Register health checks backing the Kubernetes probes
+	app.RegisterHealthChecks()
+
+	// Load chaos fault rules from file if configured
+	loadFaultRules(cfg.Fault.RulesFile)
+
+	// Reload configuration and fault rules when their files change
+	watchConfig(cfg, registry)
 
-	// Determine the server port from environment or use default
-	port := getPort()
+	// Serve the scaling signals to the HPA if enabled
+	startCustomMetricsServer(cfg.CustomMetrics, registry)
+
+	// Report how the admin API is protected
+	logAdminAuth(cfg.Admin)
+
+	// Create and configure the Gorilla Mux router
+	router := setupRouter(registry, app)
 
 	// Start the HTTP server
-	startServer(router, port)
+	startServer(router, app, cfg.Server)
+
+	// Flush spans still buffered by the exporter
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Warn().
+			Err(err).
+			Msg("Failed to flush traces")
+	}
+}
+
+// tracingOptions converts the tracing configuration into tracing options.
+func tracingOptions(cfg config.TracingConfig) tracing.Options {
+	return tracing.Options{
+		Exporter:    cfg.Exporter,
+		Endpoint:    cfg.Endpoint,
+		Insecure:    cfg.Insecure,
+		SampleRatio: cfg.SampleRatio,
+		ServiceName: cfg.ServiceName,
+	}
+}
+
+// logOptions converts the log configuration into logger options.
+func logOptions(cfg config.LogConfig) logger.Options {
+	return logger.Options{
+		Level:              cfg.Level,
+		Format:             cfg.Format,
+		File:               cfg.File,
+		FileMaxSizeMiB:     cfg.FileMaxSizeMiB,
+		FileMaxBackups:     cfg.FileMaxBackups,
+		RequestSampleEvery: cfg.RequestSampleEvery,
+		RateLimits: map[zerolog.Level]int{
+			zerolog.DebugLevel: cfg.RateLimit.Debug,
+			zerolog.InfoLevel:  cfg.RateLimit.Info,
+			zerolog.WarnLevel:  cfg.RateLimit.Warn,
+			zerolog.ErrorLevel: cfg.RateLimit.Error,
+		},
+		RedactKeys: cfg.RedactKeys,
+	}
+}
+
+// metricsOptions converts the metrics configuration into metrics options,
+// keeping the defaults for bucket lists that are not configured.
+func metricsOptions(cfg config.MetricsConfig) metrics.Options {
+	opts := metrics.DefaultOptions()
+	if len(cfg.DurationBuckets) > 0 {
+		opts.DurationBuckets = cfg.DurationBuckets
+	}
+	if len(cfg.StressDurationBuckets) > 0 {
+		for i := range opts.RouteClasses {
+			if opts.RouteClasses[i].Name == "stress" {
+				opts.RouteClasses[i].Buckets = cfg.StressDurationBuckets
+			}
+		}
+	}
+	opts.GoCollector = cfg.GoCollector
+	opts.ProcessCollector = cfg.ProcessCollector
+	opts.NativeHistograms = cfg.NativeHistograms
+	opts.Exemplars = cfg.Exemplars
+	return opts
 }
 
 // setupRouter creates and configures the Gorilla Mux router with all routes
 // and middleware. This function centralizes route configuration for clarity.
+// Middleware records metrics in registry, which also serves /metrics.
 //
 // Routes are organized into two groups:
 //   - Application routes: Business logic endpoints with logging middleware
 //   - Infrastructure routes: Metrics and health endpoints
-func setupRouter() *mux.Router {
+func setupRouter(registry *metrics.Registry, app *handlers.App) *mux.Router {
 	router := mux.NewRouter()
 
 	// Apply global middleware in order:
-	// 1. Recovery: Catches panics and prevents server crashes
-	// 2. Logging: Logs all requests with structured fields
-	router.Use(middleware.Recovery)
-	router.Use(middleware.Logging)
+	// 1. RequestID: Accepts or generates the X-Request-ID correlation ID
+	// 2. LogContext: Stores a request logger seeded with the request ID
+	// 3. Tracing: Starts a span per request, continuing incoming traces
+	// 4. Logging: Logs all requests with structured fields
+	// 5. Recovery: Catches panics and prevents server crashes, inside
+	//    Logging and Tracing so recovered requests are still recorded
+	// 6. Scaling: Records in-flight requests and request rate for the HPA
+	// 7. Chaos: Applies runtime-configured fault rules
+	router.Use(middleware.RequestID)
+	router.Use(middleware.LogContext)
+	router.Use(middleware.Tracing)
+	router.Use(middleware.Logging(registry))
+	router.Use(middleware.Recovery(registry))
+	router.Use(middleware.Scaling(registry))
+	router.Use(middleware.Chaos(registry))
 
 	// Register application routes
 	// These endpoints serve the main application functionality
 	router.HandleFunc("/", handlers.HomeHandler).Methods(http.MethodGet)
+	router.HandleFunc("/version", handlers.VersionHandler).Methods(http.MethodGet)
 	router.HandleFunc("/health", handlers.HealthHandler).Methods(http.MethodGet)
-	router.HandleFunc("/stress", handlers.StressHandler).Methods(http.MethodGet)
+	router.HandleFunc("/livez", handlers.LivenessHandler).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", handlers.ReadinessHandler).Methods(http.MethodGet)
+	router.HandleFunc("/startupz", handlers.StartupHandler).Methods(http.MethodGet)
+	router.HandleFunc("/stress", app.StressHandler).Methods(http.MethodGet)
+	router.HandleFunc("/stress/memory", app.MemoryStressHandler).Methods(http.MethodGet)
+	router.HandleFunc("/stress/jobs", app.CreateStressJobHandler).Methods(http.MethodPost)
+	router.HandleFunc("/stress/jobs", app.ListStressJobsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/stress/jobs/{id}", app.GetStressJobHandler).Methods(http.MethodGet)
+	router.HandleFunc("/stress/jobs/{id}", app.CancelStressJobHandler).Methods(http.MethodDelete)
+
+	router.HandleFunc("/fault", app.FaultHandler).Methods(http.MethodGet)
 
 	// Register infrastructure routes
 	// Prometheus metrics endpoint for observability
-	router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+	router.Handle("/metrics", registry.Handler()).Methods(http.MethodGet)
+
+	// Register admin routes
+	// Protected by ADMIN_TOKEN and disabled without it (unless
+	// ADMIN_INSECURE is set); exempt from chaos rules
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminAuth)
+	admin.HandleFunc("/config", handlers.ConfigHandler).Methods(http.MethodGet)
+	admin.HandleFunc("/loglevel", handlers.GetLogLevelHandler).Methods(http.MethodGet)
+	admin.HandleFunc("/loglevel", handlers.SetLogLevelHandler).Methods(http.MethodPut)
+	admin.HandleFunc("/loglevel", handlers.ResetLogLevelHandler).Methods(http.MethodDelete)
+	admin.HandleFunc("/faults", handlers.ListFaultRulesHandler).Methods(http.MethodGet)
+	admin.HandleFunc("/faults", handlers.CreateFaultRuleHandler).Methods(http.MethodPost)
+	admin.HandleFunc("/faults", handlers.ClearFaultRulesHandler).Methods(http.MethodDelete)
+	admin.HandleFunc("/faults/{id}", handlers.DeleteFaultRuleHandler).Methods(http.MethodDelete)
+
+	// Unmatched requests bypass router middleware; wrap their handlers so
+	// 404s and 405s still get a request ID and are traced, logged and
+	// counted under a single route label
+	unmatched := func(h http.Handler) http.Handler {
+		return middleware.RequestID(middleware.LogContext(middleware.Tracing(middleware.Logging(registry)(h))))
+	}
+	router.NotFoundHandler = unmatched(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = unmatched(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
 
 	logger.Info().
-		Int("route_count", 4).
+		Int("route_count", 22).
 		Msg("Router configured successfully")
 
 	return router
 }
 
-// getPort retrieves the server port from the PORT environment variable.
-// If not set, it returns the default port "8080".
-func getPort() string {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// logAdminAuth logs at startup whether the admin API is disabled, open or
+// protected by a token, so an unprotected admin API is never silent.
+func logAdminAuth(cfg config.AdminConfig) {
+	switch {
+	case cfg.Token != "":
+		logger.Info().Msg("Admin API requires a bearer token")
+	case cfg.Insecure:
+		logger.Warn().Msg("Admin API is served without authentication (ADMIN_INSECURE)")
+	default:
+		logger.Info().Msg("Admin API is disabled; set ADMIN_TOKEN to enable it")
+	}
+}
+
+// loadFaultRules loads chaos fault rules from the given JSON file. An empty
+// path is a no-op. The application exits if the file cannot be loaded, since
+// running a game day with half-applied rules would be misleading.
+func loadFaultRules(path string) {
+	if path == "" {
+		return
+	}
+
+	result, err := fault.LoadRulesFile(path)
+	if err != nil {
+		logger.Fatal().
+			Err(err).
+			Str("path", path).
+			Msg("Failed to load fault rules file")
+	}
+
+	logExpiredFaultRules(path, result)
+	logger.Warn().
+		Str("path", path).
+		Int("rule_count", result.Loaded).
+		Msg("Fault rules loaded from file")
+}
+
+// logExpiredFaultRules warns about the rules of a fault rules file that were
+// skipped because they have already expired.
+func logExpiredFaultRules(path string, result fault.LoadResult) {
+	if len(result.Expired) == 0 {
+		return
 	}
-	return port
+
+	logger.Warn().
+		Str("path", path).
+		Strs("expired_rules", result.Expired).
+		Msg("Skipped expired fault rules; remove them from the file")
 }
 
-// startServer starts the HTTP server on the specified port.
-// It logs startup information and handles fatal errors during server startup.
+// watchConfig starts watching the config file and the fault rules file, and
+// reloads the configuration whenever either changes. It is a no-op when
+// neither file is configured.
 //
-// The server binds to all network interfaces (0.0.0.0) on the specified port.
-func startServer(router *mux.Router, port string) {
+// The watched paths are fixed at startup: pointing rules_file at a different
+// file takes effect on the next reload, but the new file is only watched
+// after a restart.
+func watchConfig(cfg *config.Config, rec metrics.Recorder) {
+	rec.SetConfigGeneration(config.Generation())
+	rec.TrackConfigReload(true)
+
+	configFile := os.Getenv(config.FileEnv)
+	if configFile == "" && cfg.Fault.RulesFile == "" {
+		return
+	}
+
+	go func() {
+		reload := func() { reloadConfig(rec) }
+		err := config.WatchFiles(context.Background(), reload, configFile, cfg.Fault.RulesFile)
+		if err != nil {
+			logger.Error().
+				Err(err).
+				Msg("Config watcher stopped, configuration changes require a restart")
+		}
+	}()
+
 	logger.Info().
-		Str("port", port).
-		Str("version", handlers.AppVersion).
-		Msg("Starting Resilient GitOps Platform")
+		Str("config_file", configFile).
+		Str("fault_rules_file", cfg.Fault.RulesFile).
+		Msg("Watching configuration for changes")
+}
+
+// reloadConfig loads the configuration and fault rules again and applies
+// them. Either both are applied or, on any error, the previous configuration
+// and rules stay active.
+//
+// Settings read through config.Current on each use, such as stress bounds,
+// job limits and the admin token, take effect immediately. The log level,
+// request log sampling, rate limits and redacted keys are applied
+// explicitly. Server settings only take effect after a restart.
+func reloadConfig(rec metrics.Recorder) {
+	cfg, err := config.Load()
+	if err == nil {
+		err = reloadFaultRules(cfg.Fault.RulesFile)
+	}
+
+	rec.TrackConfigReload(err == nil)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Uint64("generation", config.Generation()).
+			Msg("Configuration reload failed, keeping previous configuration")
+		return
+	}
+
+	old := config.Current()
+	config.Set(cfg)
+	rec.SetConfigGeneration(config.Generation())
+
+	changed := config.Changed(old, cfg)
+	applyLogSettings(cfg.Log, changed)
+	logger.Info().
+		Uint64("generation", config.Generation()).
+		Strs("changed", changed).
+		Msg("Configuration reloaded")
+
+	if old.Server != cfg.Server {
+		logger.Warn().
+			Msg("Server settings changed; they take effect after a restart")
+	}
+	if old.Tracing != cfg.Tracing {
+		logger.Warn().
+			Msg("Tracing settings changed; they take effect after a restart")
+	}
+	if old.CustomMetrics != cfg.CustomMetrics {
+		logger.Warn().
+			Msg("Custom metrics settings changed; they take effect after a restart")
+	}
+	if slices.ContainsFunc(changed, func(key string) bool { return strings.HasPrefix(key, "log.") && !liveLogSetting(key) }) {
+		logger.Warn().
+			Msg("Log output settings changed; they take effect after a restart")
+	}
+	if slices.ContainsFunc(changed, func(key string) bool { return strings.HasPrefix(key, "metrics.") }) {
+		logger.Warn().
+			Msg("Metrics settings changed; they take effect after a restart")
+	}
+}
+
+// applyLogSettings applies the log settings that can change at runtime,
+// given the changed config keys. Sampling and rate limits are only replaced
+// when they changed, since replacing them resets their counts.
+func applyLogSettings(cfg config.LogConfig, changed []string) {
+	logger.SetLevel(cfg.Level)
+
+	opts := logOptions(cfg)
+	if slices.ContainsFunc(changed, func(key string) bool {
+		return key == "log.request_sample_every" || strings.HasPrefix(key, "log.rate_limit.")
+	}) {
+		logger.SetSampling(opts.RequestSampleEvery, opts.RateLimits)
+	}
+	if slices.Contains(changed, "log.redact_keys") {
+		logger.SetRedactKeys(opts.RedactKeys)
+	}
+}
+
+// liveLogSetting reports whether the log setting with the config key is
+// applied by applyLogSettings without a restart.
+func liveLogSetting(key string) bool {
+	switch {
+	case key == "log.level", key == "log.request_sample_every", key == "log.redact_keys":
+		return true
+	default:
+		return strings.HasPrefix(key, "log.rate_limit.")
+	}
+}
+
+// startCustomMetricsServer starts the Kubernetes custom metrics API server
+// in the background if it is enabled. It needs the in-cluster service
+// account to list pods, so the application exits if it cannot be set up.
+//
+// The server is not drained on shutdown: the HPA controller tolerates a
+// briefly unavailable pod, and peers skip pods that stop responding.
+func startCustomMetricsServer(cfg config.CustomMetricsConfig, rec metrics.Recorder) {
+	if !cfg.Enabled {
+		return
+	}
+
+	client, err := custommetrics.NewInClusterClient()
+	if err != nil {
+		logger.Fatal().
+			Err(err).
+			Msg("Failed to create Kubernetes client for custom metrics")
+	}
+
+	cert, err := custommetrics.LoadCertificateFiles(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		logger.Fatal().
+			Err(err).
+			Msg("Failed to load custom metrics certificate")
+	}
 
-	addr := ":" + port
+	roots, err := custommetrics.LoadCertPool(cfg.CAFile)
+	if err != nil {
+		logger.Fatal().
+			Err(err).
+			Msg("Failed to load the CA verifying custom metrics peers")
+	}
+
+	addr := ":" + strconv.Itoa(cfg.Port)
+	peers := custommetrics.NewPeerSignalSource(cfg.Port, roots, cfg.ServerName, cert.GetClientCertificate)
+	srv := &http.Server{
+		Addr: addr,
+		Handler: custommetrics.NewServer(client, peers, custommetrics.Options{
+			Selector:   cfg.Selector,
+			ObjectName: cfg.ObjectName,
+			Self:       cfg.PodName,
+			Local:      rec,
+			PeerCAs:    roots,
+		}),
+		TLSConfig: &tls.Config{
+			GetCertificate: cert.GetCertificate,
+			// Client certificates authenticate peers fetching /signals. They
+			// are verified by the handler, since the aggregation layer
+			// presents a proxy certificate from another CA
+			ClientAuth: tls.RequestClientCert,
+			MinVersion: tls.VersionTLS12,
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := srv.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal().
+				Err(err).
+				Msg("Custom metrics server stopped unexpectedly")
+		}
+	}()
 
 	logger.Info().
 		Str("addr", addr).
-		Msg("Server listening")
+		Str("selector", cfg.Selector).
+		Msg("Custom metrics API listening")
+}
+
+// reloadFaultRules replaces the file-based fault rules with the content of
+// path, or removes them if path is empty.
+func reloadFaultRules(path string) error {
+	if path == "" {
+		fault.ClearFileRules()
+		return nil
+	}
+
+	result, err := fault.LoadRulesFile(path)
+	if err != nil {
+		return err
+	}
 
-	if err := http.ListenAndServe(addr, router); err != nil {
+	logExpiredFaultRules(path, result)
+	return nil
+}
+
+// startServer starts the HTTP server on the configured port and blocks until
+// the server stops. It logs startup information and handles fatal errors
+// during server startup.
+//
+// The server binds to all network interfaces (0.0.0.0) on the configured port.
+// On SIGTERM or SIGINT the server shuts down gracefully (see shutdownServer).
+func startServer(router *mux.Router, app *handlers.App, cfg config.ServerConfig) {
+	info := version.Get()
+	logger.Info().
+		Int("port", cfg.Port).
+		Str("version", info.Version).
+		Str("commit", info.Commit).
+		Str("image_tag", info.ShortCommit()).
+		Str("build_date", info.BuildDate).
+		Str("go_version", info.GoVersion).
+		Bool("dirty", info.Dirty).
+		Msg("Starting Resilient GitOps Platform")
+
+	addr := ":" + strconv.Itoa(cfg.Port)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Listen for termination signals sent by Kubernetes (SIGTERM) or a
+	// developer pressing Ctrl+C (SIGINT)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
 		logger.Fatal().
 			Err(err).
 			Msg("Server failed to start")
 	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.Serve(listener)
+	}()
+
+	// The listener is bound, so the startup probe can pass from here on
+	handlers.MarkStarted()
+
+	logger.Info().
+		Str("addr", addr).
+		Msg("Server listening")
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal().
+				Err(err).
+				Msg("Server stopped unexpectedly")
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	// Restore default signal handling so a second signal terminates immediately
+	stop()
+
+	shutdownServer(srv, app, cfg.ShutdownPreStopDelay, cfg.ShutdownTimeout)
+}
+
+// shutdownServer gracefully stops the HTTP server in four phases:
+//
+//  1. Fail readiness so Kubernetes removes the pod from Service endpoints.
+//  2. Wait preStopDelay for endpoint removal to propagate to kube-proxy and
+//     load balancers, while still serving requests that arrive meanwhile.
+//  3. Stop accepting connections and wait up to drainTimeout for in-flight
+//     requests to complete.
+//  4. If the deadline expires, cancel running stress workers so their
+//     handlers can respond, then close any remaining connections.
+//     Background stress jobs are not tied to a connection and are always
+//     cancelled once draining ends.
+//
+// A summary of the shutdown is logged once all phases are complete.
+func shutdownServer(srv *http.Server, app *handlers.App, preStopDelay, drainTimeout time.Duration) {
+	start := time.Now()
+
+	handlers.SetDraining()
+
+	logger.Info().
+		Dur("pre_stop_delay", preStopDelay).
+		Dur("drain_timeout", drainTimeout).
+		Int("active_stress_runs", app.ActiveStressRuns()).
+		Msg("Shutdown signal received, readiness now failing")
+
+	time.Sleep(preStopDelay)
+
+	drainStart := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	drained := true
+	cancelledRuns := 0
+
+	if err := srv.Shutdown(ctx); err != nil {
+		drained = false
+		cancelledRuns = app.CancelStressRuns()
+
+		logger.Warn().
+			Err(err).
+			Int("cancelled_stress_runs", cancelledRuns).
+			Msg("Drain deadline exceeded, cancelling stress workers")
+
+		// Give cancelled handlers a brief moment to write their responses
+		// before forcibly closing whatever connections are still open
+		graceCtx, graceCancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer graceCancel()
+
+		if err := srv.Shutdown(graceCtx); err != nil {
+			_ = srv.Close()
+		}
+	} else if jobs := app.CancelStressRuns(); jobs > 0 {
+		cancelledRuns = jobs
+
+		logger.Info().
+			Int("cancelled_stress_runs", cancelledRuns).
+			Msg("Cancelled background stress jobs")
+	}
+
+	logger.Info().
+		Bool("drained", drained).
+		Int("cancelled_stress_runs", cancelledRuns).
+		Dur("drain_duration", time.Since(drainStart)).
+		Dur("shutdown_duration", time.Since(start)).
+		Msg("Server shutdown complete")
 }
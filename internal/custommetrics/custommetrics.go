@@ -0,0 +1,410 @@
+// Package custommetrics implements a minimal Kubernetes custom metrics API
+// (custom.metrics.k8s.io/v1beta2) serving the application's scaling signals,
+// so a HorizontalPodAutoscaler can scale on them without prometheus-adapter.
+//
+// The server is registered with the Kubernetes API aggregation layer through
+// an APIService. The pod that receives a request lists the application pods
+// through a PodLister and collects each pod's signals through a
+// SignalSource, then answers with a MetricValueList:
+//
+//   - GET /apis/custom.metrics.k8s.io/v1beta2/namespaces/{ns}/pods/{name}/{metric}
+//     returns the signal of one pod, or of every selected pod when {name} is
+//     "*" (the form used by HPA Pods metrics).
+//   - GET /apis/custom.metrics.k8s.io/v1beta2/namespaces/{ns}/{resource}/{name}/{metric}
+//     with resource "deployments.apps" or "services" returns the sum over
+//     all selected pods (the form used by HPA Object metrics). Only the
+//     application's own Deployment and Service, named by
+//     Options.ObjectName, are served.
+//
+// Each pod also serves its own signals at GET /signals, which is what the
+// peer SignalSource fetches. Only peers presenting a client certificate
+// issued by Options.PeerCAs may read them. Both dependencies are interfaces,
+// so the server can be exercised against fakes without a cluster.
+//
+// Example usage:
+//
+//	client, err := custommetrics.NewInClusterClient()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	cert, err := custommetrics.LoadCertificateFiles("tls.crt", "tls.key")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	roots, err := custommetrics.LoadCertPool("ca.crt")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	peers := custommetrics.NewPeerSignalSource(6443, roots, "go-gitops-app-custom-metrics", cert.GetClientCertificate)
+//	srv := &http.Server{
+//		Addr: ":6443",
+//		Handler: custommetrics.NewServer(client, peers, custommetrics.Options{
+//			Selector:   "app=go-gitops-app",
+//			ObjectName: "go-gitops-app",
+//			Self:       os.Getenv("POD_NAME"),
+//			Local:      registry,
+//			PeerCAs:    roots,
+//		}),
+//		TLSConfig: &tls.Config{
+//			GetCertificate: cert.GetCertificate,
+//			ClientAuth:     tls.RequestClientCert,
+//		},
+//	}
+//	srv.ListenAndServeTLS("", "")
+package custommetrics
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+const (
+	// Group is the API group served by this package.
+	Group = "custom.metrics.k8s.io"
+
+	// Version is the API version served by this package.
+	Version = "v1beta2"
+
+	// groupVersion is the group/version string used in API objects.
+	groupVersion = Group + "/" + Version
+
+	// windowSeconds is reported as the measurement window of every value.
+	windowSeconds = 30
+
+	// defaultFetchTimeout bounds collecting signals from a single pod.
+	defaultFetchTimeout = 2 * time.Second
+)
+
+// objectKinds maps the object resources supported for Object metrics to the
+// kind and apiVersion reported in describedObject.
+var objectKinds = map[string]ObjectReference{
+	"deployments.apps": {Kind: "Deployment", APIVersion: "apps/v1"},
+	"services":         {Kind: "Service", APIVersion: "/v1"},
+}
+
+// Pod identifies an application pod whose signals can be collected.
+type Pod struct {
+	Name      string
+	Namespace string
+	IP        string
+}
+
+// PodLister lists the running application pods in a namespace that match a
+// label selector.
+type PodLister interface {
+	ListPods(ctx context.Context, namespace, selector string) ([]Pod, error)
+}
+
+// SignalSource collects the current scaling signals of a pod.
+type SignalSource interface {
+	Signals(ctx context.Context, pod Pod) (metrics.ScalingSignals, error)
+}
+
+// Options configures a Server.
+type Options struct {
+	// Selector is the label selector of the application pods. It is used
+	// when a request carries no labelSelector.
+	Selector string
+
+	// ObjectName is the name of the application's Deployment and Service.
+	// Object metrics are served only for objects with this name, since the
+	// pods of other objects are unknown. Empty disables Object metrics.
+	ObjectName string
+
+	// Self is the name of the pod running the server. Its signals are read
+	// locally instead of over the network. Empty disables the shortcut.
+	Self string
+
+	// Local provides the signals of the pod running the server, both for
+	// Self and for peers fetching /signals. Defaults to metrics.Nop, which
+	// reports zero signals.
+	Local metrics.Recorder
+
+	// FetchTimeout bounds collecting signals from a single pod. Defaults
+	// to 2s.
+	FetchTimeout time.Duration
+
+	// PeerCAs verifies the client certificates of peers fetching /signals.
+	// Requests without a certificate issued by PeerCAs are forbidden, and
+	// nil forbids them all.
+	PeerCAs *x509.CertPool
+}
+
+// Server serves the custom metrics API.
+type Server struct {
+	pods    PodLister
+	signals SignalSource
+	opts    Options
+	router  *mux.Router
+}
+
+// NewServer creates a custom metrics API server backed by the given pod
+// lister and signal source.
+func NewServer(pods PodLister, signals SignalSource, opts Options) *Server {
+	if opts.FetchTimeout <= 0 {
+		opts.FetchTimeout = defaultFetchTimeout
+	}
+	if opts.Local == nil {
+		opts.Local = metrics.Nop()
+	}
+
+	s := &Server{
+		pods:    pods,
+		signals: signals,
+		opts:    opts,
+		router:  mux.NewRouter(),
+	}
+
+	base := "/apis/" + groupVersion
+	s.router.HandleFunc("/signals", s.handleSignals).Methods(http.MethodGet)
+	s.router.HandleFunc("/apis", s.handleGroupList).Methods(http.MethodGet)
+	s.router.HandleFunc("/apis/"+Group, s.handleGroup).Methods(http.MethodGet)
+	s.router.HandleFunc(base, s.handleResources).Methods(http.MethodGet)
+	s.router.HandleFunc(base+"/namespaces/{namespace}/pods/{name}/{metric}", s.handlePodMetric).Methods(http.MethodGet)
+	s.router.HandleFunc(base+"/namespaces/{namespace}/{resource}/{name}/{metric}", s.handleObjectMetric).Methods(http.MethodGet)
+	s.router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sendStatus(w, http.StatusNotFound, "NotFound", "the server could not find the requested resource")
+	})
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// handleSignals returns this pod's own scaling signals to peers.
+func (s *Server) handleSignals(w http.ResponseWriter, r *http.Request) {
+	if !verifiedPeer(r, s.opts.PeerCAs) {
+		sendStatus(w, http.StatusForbidden, "Forbidden", "signals are served to peer pods only")
+		return
+	}
+	response.SendJSON(w, http.StatusOK, s.opts.Local.ScalingSignals())
+}
+
+// handleGroupList serves API discovery for the aggregation layer.
+func (s *Server) handleGroupList(w http.ResponseWriter, r *http.Request) {
+	response.SendJSON(w, http.StatusOK, map[string]any{
+		"kind":       "APIGroupList",
+		"apiVersion": "v1",
+		"groups":     []any{apiGroup()},
+	})
+}
+
+// handleGroup serves discovery for the custom metrics API group.
+func (s *Server) handleGroup(w http.ResponseWriter, r *http.Request) {
+	group := apiGroup()
+	group["kind"] = "APIGroup"
+	group["apiVersion"] = "v1"
+	response.SendJSON(w, http.StatusOK, group)
+}
+
+// handleResources lists the metrics available for each resource.
+func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
+	resources := make([]map[string]any, 0)
+	for _, resource := range []string{"pods", "deployments.apps", "services"} {
+		for _, metric := range metrics.ScalingSignalNames() {
+			resources = append(resources, map[string]any{
+				"name":         resource + "/" + metric,
+				"singularName": "",
+				"namespaced":   true,
+				"kind":         "MetricValueList",
+				"verbs":        []string{"get"},
+			})
+		}
+	}
+
+	response.SendJSON(w, http.StatusOK, map[string]any{
+		"kind":         "APIResourceList",
+		"apiVersion":   "v1",
+		"groupVersion": groupVersion,
+		"resources":    resources,
+	})
+}
+
+// handlePodMetric serves a signal for one pod, or for all selected pods
+// when the name is "*".
+func (s *Server) handlePodMetric(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace, name, metric := vars["namespace"], vars["name"], vars["metric"]
+
+	if !knownMetric(metric) {
+		sendStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("metric %s is not served", metric))
+		return
+	}
+
+	pods, ok := s.listPods(w, r, namespace)
+	if !ok {
+		return
+	}
+
+	if name != "*" {
+		pods = filterPods(pods, name)
+		if len(pods) == 0 {
+			sendStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("pod %s/%s not found", namespace, name))
+			return
+		}
+	}
+
+	collected := s.collect(r.Context(), pods)
+	if name != "*" && len(collected) == 0 {
+		sendStatus(w, http.StatusServiceUnavailable, "ServiceUnavailable", fmt.Sprintf("signals of pod %s/%s are unavailable", namespace, name))
+		return
+	}
+
+	now := time.Now().UTC()
+	items := make([]MetricValue, 0, len(collected))
+	for _, c := range collected {
+		items = append(items, newMetricValue(ObjectReference{
+			Kind:       "Pod",
+			Namespace:  c.pod.Namespace,
+			Name:       c.pod.Name,
+			APIVersion: "/v1",
+		}, metric, c.signals.Values()[metric], now))
+	}
+
+	response.SendJSON(w, http.StatusOK, newMetricValueList(items))
+}
+
+// handleObjectMetric serves a signal summed over all selected pods, described
+// as belonging to the application's Deployment or Service.
+func (s *Server) handleObjectMetric(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace, resource, name, metric := vars["namespace"], vars["resource"], vars["name"], vars["metric"]
+
+	kind, ok := objectKinds[resource]
+	if !ok {
+		sendStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("resource %s is not served", resource))
+		return
+	}
+	if name != s.opts.ObjectName || name == "" {
+		sendStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("metrics of %s %s/%s are not served", resource, namespace, name))
+		return
+	}
+	if !knownMetric(metric) {
+		sendStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("metric %s is not served", metric))
+		return
+	}
+
+	pods, ok := s.listPods(w, r, namespace)
+	if !ok {
+		return
+	}
+
+	var total float64
+	for _, c := range s.collect(r.Context(), pods) {
+		total += c.signals.Values()[metric]
+	}
+
+	kind.Namespace = namespace
+	kind.Name = name
+	item := newMetricValue(kind, metric, total, time.Now().UTC())
+
+	response.SendJSON(w, http.StatusOK, newMetricValueList([]MetricValue{item}))
+}
+
+// listPods lists the application pods in namespace matching the
+// labelSelector of r, or Options.Selector if it has none. When listing
+// fails, it writes an error response and returns false.
+func (s *Server) listPods(w http.ResponseWriter, r *http.Request, namespace string) ([]Pod, bool) {
+	selector := r.URL.Query().Get("labelSelector")
+	if selector == "" {
+		selector = s.opts.Selector
+	}
+
+	pods, err := s.pods.ListPods(r.Context(), namespace, selector)
+	if err != nil {
+		logger.FromContext(r.Context()).Error().
+			Err(err).
+			Str("namespace", namespace).
+			Str("selector", selector).
+			Msg("Custom metrics: failed to list pods")
+
+		sendStatus(w, http.StatusInternalServerError, "InternalError", "unable to list pods: "+err.Error())
+		return nil, false
+	}
+	return pods, true
+}
+
+// collected pairs a pod with its signals.
+type collected struct {
+	pod     Pod
+	signals metrics.ScalingSignals
+}
+
+// collect fetches the signals of all pods concurrently. Pods whose signals
+// cannot be fetched are logged and left out, so one unreachable pod does not
+// block scaling decisions. Results keep the order of pods.
+func (s *Server) collect(ctx context.Context, pods []Pod) []collected {
+	results := make([]*collected, len(pods))
+
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Go(func() {
+			if pod.Name == s.opts.Self && s.opts.Self != "" {
+				results[i] = &collected{pod: pod, signals: s.opts.Local.ScalingSignals()}
+				return
+			}
+
+			fetchCtx, cancel := context.WithTimeout(ctx, s.opts.FetchTimeout)
+			defer cancel()
+
+			signals, err := s.signals.Signals(fetchCtx, pod)
+			if err != nil {
+				logger.FromContext(ctx).Warn().
+					Err(err).
+					Str("pod", pod.Name).
+					Str("namespace", pod.Namespace).
+					Msg("Custom metrics: failed to fetch pod signals")
+				return
+			}
+			results[i] = &collected{pod: pod, signals: signals}
+		})
+	}
+	wg.Wait()
+
+	out := make([]collected, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			out = append(out, *result)
+		}
+	}
+	return out
+}
+
+// filterPods returns the pods with the given name.
+func filterPods(pods []Pod, name string) []Pod {
+	for _, pod := range pods {
+		if pod.Name == name {
+			return []Pod{pod}
+		}
+	}
+	return nil
+}
+
+// knownMetric reports whether metric is a served scaling signal.
+func knownMetric(metric string) bool {
+	return slices.Contains(metrics.ScalingSignalNames(), metric)
+}
+
+// apiGroup describes the custom metrics API group for discovery.
+func apiGroup() map[string]any {
+	version := map[string]string{"groupVersion": groupVersion, "version": Version}
+	return map[string]any{
+		"name":             Group,
+		"versions":         []any{version},
+		"preferredVersion": version,
+	}
+}
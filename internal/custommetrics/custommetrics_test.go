@@ -0,0 +1,320 @@
+package custommetrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// fakePodLister is a PodLister returning fixed pods and recording the
+// arguments of the last call.
+type fakePodLister struct {
+	pods []Pod
+	err  error
+
+	namespace, selector string
+}
+
+func (f *fakePodLister) ListPods(_ context.Context, namespace, selector string) ([]Pod, error) {
+	f.namespace, f.selector = namespace, selector
+	return f.pods, f.err
+}
+
+// fakeSignalSource is a SignalSource returning fixed signals per pod name.
+// Pods without signals fail to be fetched.
+type fakeSignalSource map[string]metrics.ScalingSignals
+
+func (f fakeSignalSource) Signals(_ context.Context, pod Pod) (metrics.ScalingSignals, error) {
+	signals, ok := f[pod.Name]
+	if !ok {
+		return metrics.ScalingSignals{}, errors.New("pod unreachable")
+	}
+	return signals, nil
+}
+
+// fakeLocal is a Recorder reporting fixed scaling signals.
+type fakeLocal struct {
+	metrics.Recorder
+	signals metrics.ScalingSignals
+}
+
+func (f fakeLocal) ScalingSignals() metrics.ScalingSignals {
+	return f.signals
+}
+
+// newTestServer creates a Server for three pods in namespace "apps": "a"
+// (the server itself), "b" and the unreachable "c".
+func newTestServer(lister *fakePodLister) *Server {
+	if lister.pods == nil && lister.err == nil {
+		lister.pods = []Pod{
+			{Name: "a", Namespace: "apps", IP: "10.0.0.1"},
+			{Name: "b", Namespace: "apps", IP: "10.0.0.2"},
+			{Name: "c", Namespace: "apps", IP: "10.0.0.3"},
+		}
+	}
+	signals := fakeSignalSource{
+		"b": {InFlightRequests: 2, StressQueueDepth: 1, RequestRate: 0.25},
+	}
+	return NewServer(lister, signals, Options{
+		Selector:   "app=test",
+		ObjectName: "test-app",
+		Self:       "a",
+		Local:      fakeLocal{Recorder: metrics.Nop(), signals: metrics.ScalingSignals{InFlightRequests: 3, StressQueueDepth: 2}},
+	})
+}
+
+func serve(t *testing.T, srv http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestDiscovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		wantKind string
+	}{
+		{name: "group list", path: "/apis", wantKind: "APIGroupList"},
+		{name: "group", path: "/apis/custom.metrics.k8s.io", wantKind: "APIGroup"},
+		{name: "resources", path: "/apis/custom.metrics.k8s.io/v1beta2", wantKind: "APIResourceList"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, newTestServer(&fakePodLister{}), tt.path)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body["kind"] != tt.wantKind {
+				t.Errorf("kind = %v, want %s", body["kind"], tt.wantKind)
+			}
+			if !strings.Contains(rec.Body.String(), groupVersion) {
+				t.Errorf("body does not mention %s: %s", groupVersion, rec.Body)
+			}
+		})
+	}
+}
+
+func TestMetricList(t *testing.T) {
+	rec := serve(t, newTestServer(&fakePodLister{}), "/apis/custom.metrics.k8s.io/v1beta2")
+
+	var body struct {
+		Resources []struct {
+			Name       string `json:"name"`
+			Namespaced bool   `json:"namespaced"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, resource := range body.Resources {
+		if !resource.Namespaced {
+			t.Errorf("resource %s is not namespaced", resource.Name)
+		}
+		got[resource.Name] = true
+	}
+	for _, resource := range []string{"pods", "deployments.apps", "services"} {
+		for _, metric := range metrics.ScalingSignalNames() {
+			if name := resource + "/" + metric; !got[name] {
+				t.Errorf("resource list lacks %s", name)
+			}
+		}
+	}
+	if want := 3 * len(metrics.ScalingSignalNames()); len(body.Resources) != want {
+		t.Errorf("got %d resources, want %d", len(body.Resources), want)
+	}
+}
+
+func TestMetricValues(t *testing.T) {
+	const base = "/apis/custom.metrics.k8s.io/v1beta2/namespaces/apps"
+
+	tests := []struct {
+		name         string
+		path         string
+		listErr      error
+		wantStatus   int
+		wantSelector string
+		wantValues   map[string]string
+		wantKind     string
+	}{
+		{
+			name:         "all pods skip unreachable ones",
+			path:         base + "/pods/*/scaling_in_flight_requests",
+			wantStatus:   http.StatusOK,
+			wantSelector: "app=test",
+			wantValues:   map[string]string{"a": "3000m", "b": "2000m"},
+			wantKind:     "Pod",
+		},
+		{
+			name:         "labelSelector overrides the default selector",
+			path:         base + "/pods/*/scaling_request_rate?labelSelector=tier%3Dweb",
+			wantStatus:   http.StatusOK,
+			wantSelector: "tier=web",
+			wantValues:   map[string]string{"a": "0m", "b": "250m"},
+			wantKind:     "Pod",
+		},
+		{
+			name:         "single pod",
+			path:         base + "/pods/b/scaling_stress_queue_depth",
+			wantStatus:   http.StatusOK,
+			wantSelector: "app=test",
+			wantValues:   map[string]string{"b": "1000m"},
+			wantKind:     "Pod",
+		},
+		{
+			name:       "unknown pod",
+			path:       base + "/pods/x/scaling_in_flight_requests",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unreachable pod",
+			path:       base + "/pods/c/scaling_in_flight_requests",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "unknown pod metric",
+			path:       base + "/pods/*/cpu_usage",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "pod listing fails",
+			path:       base + "/pods/*/scaling_in_flight_requests",
+			listErr:    errors.New("forbidden"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "deployment sums the pods",
+			path:         base + "/deployments.apps/test-app/scaling_stress_queue_depth",
+			wantStatus:   http.StatusOK,
+			wantSelector: "app=test",
+			wantValues:   map[string]string{"test-app": "3000m"},
+			wantKind:     "Deployment",
+		},
+		{
+			name:         "service honors labelSelector",
+			path:         base + "/services/test-app/scaling_in_flight_requests?labelSelector=tier%3Dweb",
+			wantStatus:   http.StatusOK,
+			wantSelector: "tier=web",
+			wantValues:   map[string]string{"test-app": "5000m"},
+			wantKind:     "Service",
+		},
+		{
+			name:       "other deployment",
+			path:       base + "/deployments.apps/other/scaling_stress_queue_depth",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unsupported resource",
+			path:       base + "/statefulsets.apps/test-app/scaling_stress_queue_depth",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown object metric",
+			path:       base + "/deployments.apps/test-app/cpu_usage",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lister := &fakePodLister{err: tt.listErr}
+			rec := serve(t, newTestServer(lister), tt.path)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var status map[string]any
+				if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || status["kind"] != "Status" {
+					t.Errorf("error body is not a Status: %s", rec.Body)
+				}
+				return
+			}
+
+			if lister.namespace != "apps" || lister.selector != tt.wantSelector {
+				t.Errorf("ListPods(%q, %q), want (%q, %q)", lister.namespace, lister.selector, "apps", tt.wantSelector)
+			}
+
+			var list MetricValueList
+			if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			got := make(map[string]string)
+			for _, item := range list.Items {
+				if item.DescribedObject.Kind != tt.wantKind {
+					t.Errorf("describedObject.kind = %s, want %s", item.DescribedObject.Kind, tt.wantKind)
+				}
+				got[item.DescribedObject.Name] = item.Value
+			}
+			if len(got) != len(tt.wantValues) {
+				t.Errorf("values = %v, want %v", got, tt.wantValues)
+			}
+			for name, want := range tt.wantValues {
+				if got[name] != want {
+					t.Errorf("value of %s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestObjectMetricsDisabledWithoutObjectName(t *testing.T) {
+	srv := NewServer(&fakePodLister{}, fakeSignalSource{}, Options{Selector: "app=test"})
+
+	rec := serve(t, srv, "/apis/custom.metrics.k8s.io/v1beta2/namespaces/apps/deployments.apps/test-app/scaling_stress_queue_depth")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestPeerSignalSourceVerifiesCertificates(t *testing.T) {
+	peer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.SendJSON(w, http.StatusOK, metrics.ScalingSignals{InFlightRequests: 4})
+	}))
+	defer peer.Close()
+
+	host, portStr, err := net.SplitHostPort(peer.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	pod := Pod{Name: "peer", Namespace: "apps", IP: host}
+
+	trusted := peer.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	// The httptest certificate is valid for example.com
+	signals, err := NewPeerSignalSource(port, trusted, "example.com", nil).Signals(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("Signals() with the peer CA: %v", err)
+	}
+	if signals.InFlightRequests != 4 {
+		t.Errorf("InFlightRequests = %v, want 4", signals.InFlightRequests)
+	}
+
+	if _, err := NewPeerSignalSource(port, trusted, "other.example", nil).Signals(context.Background(), pod); err == nil {
+		t.Error("Signals() succeeded for a server name the certificate does not cover")
+	}
+
+	if _, err := NewPeerSignalSource(port, nil, "example.com", nil).Signals(context.Background(), pod); err == nil {
+		t.Error("Signals() succeeded without trusting the peer CA")
+	}
+}
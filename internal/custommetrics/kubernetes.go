@@ -0,0 +1,198 @@
+package custommetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+)
+
+// serviceAccountDir holds the credentials Kubernetes mounts into every pod.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// InClusterCAFile is the cluster CA certificate mounted with the service
+// account.
+const InClusterCAFile = serviceAccountDir + "/ca.crt"
+
+// LoadCertPool reads the PEM certificates in file, such as InClusterCAFile,
+// into a certificate pool.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s contains no certificates", file)
+	}
+	return pool, nil
+}
+
+// KubernetesClient is a minimal Kubernetes API client that lists pods using
+// the pod's service account. It implements PodLister.
+type KubernetesClient struct {
+	baseURL   string
+	tokenFile string
+	client    *http.Client
+}
+
+// NewInClusterClient creates a KubernetesClient from the in-cluster
+// environment: the KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT
+// variables and the mounted service account token and CA certificate.
+func NewInClusterClient() (*KubernetesClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	pool, err := LoadCertPool(InClusterCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("load service account CA: %w", err)
+	}
+
+	return &KubernetesClient{
+		baseURL:   "https://" + net.JoinHostPort(host, port),
+		tokenFile: serviceAccountDir + "/token",
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+			},
+		},
+	}, nil
+}
+
+// podList is the subset of the Kubernetes PodList used by ListPods.
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+			PodIP string `json:"podIP"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ListPods lists the running pods with an IP in namespace matching selector.
+func (c *KubernetesClient) ListPods(ctx context.Context, namespace, selector string) ([]Pod, error) {
+	// The token is read on every call because bound service account tokens
+	// are rotated by the kubelet
+	token, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("read service account token: %w", err)
+	}
+
+	endpoint := c.baseURL + "/api/v1/namespaces/" + url.PathEscape(namespace) + "/pods"
+	if selector != "" {
+		endpoint += "?labelSelector=" + url.QueryEscape(selector)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("list pods: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var list podList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode pod list: %w", err)
+	}
+
+	pods := make([]Pod, 0, len(list.Items))
+	for _, item := range list.Items {
+		if item.Status.Phase != "Running" || item.Status.PodIP == "" {
+			continue
+		}
+		pods = append(pods, Pod{
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			IP:        item.Status.PodIP,
+		})
+	}
+	return pods, nil
+}
+
+// PeerSignalSource fetches scaling signals from the /signals endpoint of the
+// custom metrics server running in each pod. It implements SignalSource.
+type PeerSignalSource struct {
+	port   int
+	client *http.Client
+}
+
+// NewPeerSignalSource creates a PeerSignalSource for peers serving the
+// custom metrics API on port.
+//
+// Peer serving certificates are verified against roots, the CA that issues
+// the serving certificates of the application. Peers are addressed by pod
+// IP, which serving certificates do not cover, so they are verified for
+// serverName instead, such as the name of the custom metrics Service.
+// clientCert provides the certificate presented to peers, which only serve
+// their signals to clients it authenticates.
+func NewPeerSignalSource(port int, roots *x509.CertPool, serverName string, clientCert func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) *PeerSignalSource {
+	return &PeerSignalSource{
+		port: port,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:              roots,
+					ServerName:           serverName,
+					GetClientCertificate: clientCert,
+					MinVersion:           tls.VersionTLS12,
+				},
+			},
+		},
+	}
+}
+
+// Signals fetches the scaling signals of pod.
+func (s *PeerSignalSource) Signals(ctx context.Context, pod Pod) (metrics.ScalingSignals, error) {
+	endpoint := "https://" + net.JoinHostPort(pod.IP, strconv.Itoa(s.port)) + "/signals"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return metrics.ScalingSignals{}, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return metrics.ScalingSignals{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return metrics.ScalingSignals{}, fmt.Errorf("fetch signals: %s", resp.Status)
+	}
+
+	var signals metrics.ScalingSignals
+	if err := json.NewDecoder(resp.Body).Decode(&signals); err != nil {
+		return metrics.ScalingSignals{}, fmt.Errorf("decode signals: %w", err)
+	}
+	return signals, nil
+}
@@ -0,0 +1,96 @@
+package custommetrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateFiles provides a certificate and key loaded from files, such as
+// a cert-manager secret. The files are loaded again when either changes, so
+// a renewed certificate is picked up without a restart. It is safe for
+// concurrent use.
+type CertificateFiles struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+// LoadCertificateFiles loads the certificate and key in certFile and keyFile.
+func LoadCertificateFiles(certFile, keyFile string) (*CertificateFiles, error) {
+	c := &CertificateFiles{certFile: certFile, keyFile: keyFile}
+	if _, err := c.current(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate for servers.
+func (c *CertificateFiles) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.current()
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate for
+// clients.
+func (c *CertificateFiles) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return c.current()
+}
+
+// current returns the loaded certificate, reloading it first if either file
+// changed. If reloading fails, for example while the files are being
+// replaced, the previous certificate is kept.
+func (c *CertificateFiles) current() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var modTimes [2]time.Time
+	for i, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			if c.cert != nil {
+				return c.cert, nil
+			}
+			return nil, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	if c.cert != nil && modTimes == c.modTimes {
+		return c.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	c.cert, c.modTimes = &cert, modTimes
+	return c.cert, nil
+}
+
+// verifiedPeer reports whether the client of r presented a certificate for
+// client authentication issued by roots. The TLS server must request client
+// certificates without verifying them, since the aggregation layer presents
+// its own proxy client certificate.
+func verifiedPeer(r *http.Request, roots *x509.CertPool) bool {
+	if roots == nil || r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := r.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
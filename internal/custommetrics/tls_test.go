@@ -0,0 +1,211 @@
+package custommetrics
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+)
+
+// testCA is a certificate authority issuing test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue writes a client and server certificate issued by the CA, and its
+// key, to PEM files in dir. It returns the file paths.
+func (ca *testCA) issue(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	write := func(file, blockType string, der []byte) {
+		if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(certFile, "CERTIFICATE", der)
+	write(keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func TestCertificateFilesReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	certFile, keyFile := ca.issue(t, dir, "first")
+	files, err := LoadCertificateFiles(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("LoadCertificateFiles() error = %v", err)
+	}
+
+	// commonName returns the subject of the certificate currently served.
+	commonName := func() string {
+		t.Helper()
+		cert, err := files.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if got := commonName(); got != "first" {
+		t.Fatalf("serving %q, want first", got)
+	}
+
+	// A renewed certificate is picked up once the files change
+	ca.issue(t, dir, "renewed")
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := commonName(); got != "renewed" {
+		t.Errorf("serving %q after renewal, want renewed", got)
+	}
+
+	// A broken or missing file keeps the previous certificate
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(); got != "renewed" {
+		t.Errorf("serving %q after a broken renewal, want renewed", got)
+	}
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(); got != "renewed" {
+		t.Errorf("serving %q with a missing key, want renewed", got)
+	}
+
+	if _, err := LoadCertificateFiles(certFile, keyFile); err == nil {
+		t.Error("LoadCertificateFiles() succeeded without a key")
+	}
+}
+
+func TestSignalsServedToPeersOnly(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, t.TempDir(), "peer")
+	files, err := LoadCertificateFiles(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := httptest.NewUnstartedServer(NewServer(&fakePodLister{}, fakeSignalSource{}, Options{
+		Local:   fakeLocal{Recorder: metrics.Nop(), signals: metrics.ScalingSignals{InFlightRequests: 4}},
+		PeerCAs: ca.pool,
+	}))
+	peer.TLS = &tls.Config{GetCertificate: files.GetCertificate, ClientAuth: tls.RequestClientCert}
+	peer.StartTLS()
+	defer peer.Close()
+
+	host, portStr, err := net.SplitHostPort(peer.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	pod := Pod{Name: "peer", Namespace: "apps", IP: host}
+
+	signals, err := NewPeerSignalSource(port, ca.pool, "peer", files.GetClientCertificate).Signals(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("Signals() with a peer certificate: %v", err)
+	}
+	if signals.InFlightRequests != 4 {
+		t.Errorf("InFlightRequests = %v, want 4", signals.InFlightRequests)
+	}
+
+	other := newTestCA(t)
+	otherCert, otherKey := other.issue(t, t.TempDir(), "peer")
+	otherFiles, err := LoadCertificateFiles(otherCert, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		clientCert func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	}{
+		{name: "no client certificate"},
+		{name: "certificate from another CA", clientCert: otherFiles.GetClientCertificate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPeerSignalSource(port, ca.pool, "peer", tt.clientCert).Signals(context.Background(), pod)
+			if err == nil || err.Error() != "fetch signals: "+strconv.Itoa(http.StatusForbidden)+" Forbidden" {
+				t.Errorf("Signals() error = %v, want 403 Forbidden", err)
+			}
+		})
+	}
+}
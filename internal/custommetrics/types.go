@@ -0,0 +1,83 @@
+package custommetrics
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// ObjectReference identifies the Kubernetes object a metric value describes.
+type ObjectReference struct {
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	APIVersion string `json:"apiVersion"`
+}
+
+// MetricIdentifier names a metric.
+type MetricIdentifier struct {
+	Name string `json:"name"`
+}
+
+// MetricValue is a single metric value for an object.
+type MetricValue struct {
+	DescribedObject ObjectReference  `json:"describedObject"`
+	Metric          MetricIdentifier `json:"metric"`
+	Timestamp       time.Time        `json:"timestamp"`
+	WindowSeconds   int64            `json:"windowSeconds"`
+
+	// Value is a Kubernetes resource quantity, such as "1500m".
+	Value string `json:"value"`
+}
+
+// MetricValueList is the response body of metric requests.
+type MetricValueList struct {
+	Kind       string         `json:"kind"`
+	APIVersion string         `json:"apiVersion"`
+	Metadata   map[string]any `json:"metadata"`
+	Items      []MetricValue  `json:"items"`
+}
+
+// newMetricValue creates a MetricValue for object.
+func newMetricValue(object ObjectReference, metric string, value float64, now time.Time) MetricValue {
+	return MetricValue{
+		DescribedObject: object,
+		Metric:          MetricIdentifier{Name: metric},
+		Timestamp:       now,
+		WindowSeconds:   windowSeconds,
+		Value:           quantity(value),
+	}
+}
+
+// newMetricValueList wraps items in a MetricValueList.
+func newMetricValueList(items []MetricValue) MetricValueList {
+	return MetricValueList{
+		Kind:       "MetricValueList",
+		APIVersion: groupVersion,
+		Metadata:   map[string]any{},
+		Items:      items,
+	}
+}
+
+// quantity formats value as a Kubernetes quantity in milli-units, which
+// keeps fractional values such as request rates exact to 0.001.
+func quantity(value float64) string {
+	return strconv.FormatInt(int64(math.Round(value*1000)), 10) + "m"
+}
+
+// sendStatus writes a Kubernetes Status error, the error format expected by
+// API clients such as the HPA controller.
+func sendStatus(w http.ResponseWriter, code int, reason, message string) {
+	response.SendJSON(w, code, map[string]any{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"metadata":   map[string]any{},
+		"status":     "Failure",
+		"message":    message,
+		"reason":     reason,
+		"code":       code,
+	})
+}
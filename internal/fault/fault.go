@@ -0,0 +1,132 @@
+// Package fault provides building blocks for synthetic fault injection.
+//
+// Faults are used to demonstrate latency- and error-rate-based alerting on
+// top of the application's request metrics. The package models delay
+// distributions and error injection independently of any HTTP endpoint so
+// that the same primitives can be reused wherever faults are injected.
+//
+// Example usage:
+//
+//	latency := fault.Latency{Distribution: fault.Uniform, Min: 50 * time.Millisecond, Max: 200 * time.Millisecond}
+//	if err := fault.Sleep(ctx, latency.Sample()); err != nil {
+//		return err
+//	}
+package fault
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Distribution names a delay distribution.
+type Distribution string
+
+const (
+	// Fixed always delays by Latency.Delay.
+	Fixed Distribution = "fixed"
+
+	// Uniform delays by a value drawn uniformly from [Latency.Min, Latency.Max].
+	Uniform Distribution = "uniform"
+
+	// Normal delays by a value drawn from a normal distribution with mean
+	// Latency.Delay and standard deviation Latency.StdDev, clamped at zero.
+	Normal Distribution = "normal"
+
+	// LongTail delays by Latency.Delay, except for the slowest
+	// (100 - Latency.Percentile)% of requests which are delayed by
+	// Latency.Tail. Use it to move a specific percentile, e.g. p99.
+	LongTail Distribution = "longtail"
+)
+
+// Latency describes a delay distribution. Which fields are used depends on
+// the Distribution; see the Distribution constants.
+type Latency struct {
+	Distribution Distribution
+	Delay        time.Duration
+	Min          time.Duration
+	Max          time.Duration
+	StdDev       time.Duration
+	Tail         time.Duration
+	Percentile   float64
+}
+
+// Sample draws a single delay from the distribution. An empty or unknown
+// distribution yields no delay.
+func (l Latency) Sample() time.Duration {
+	switch l.Distribution {
+	case Fixed:
+		return l.Delay
+	case Uniform:
+		if l.Max <= l.Min {
+			return l.Min
+		}
+		return l.Min + rand.N(l.Max-l.Min+1)
+	case Normal:
+		d := time.Duration(rand.NormFloat64()*float64(l.StdDev)) + l.Delay
+		return max(d, 0)
+	case LongTail:
+		if rand.Float64()*100 >= l.Percentile {
+			return l.Tail
+		}
+		return l.Delay
+	default:
+		return 0
+	}
+}
+
+// Validate checks that the fields required by the distribution are set and
+// consistent.
+func (l Latency) Validate() error {
+	switch l.Distribution {
+	case "", Fixed, Normal:
+		return nil
+	case Uniform:
+		if l.Max < l.Min {
+			return fmt.Errorf("uniform latency requires min <= max")
+		}
+		return nil
+	case LongTail:
+		if l.Percentile <= 0 || l.Percentile >= 100 {
+			return fmt.Errorf("longtail latency requires a percentile between 0 and 100")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown latency distribution %q", l.Distribution)
+	}
+}
+
+// Errors describes synthetic error injection. Each request fails with
+// probability Rate using a status code chosen uniformly from Codes.
+type Errors struct {
+	Rate  float64
+	Codes []int
+}
+
+// Pick decides whether to inject an error. It returns the status code to
+// respond with and true, or 0 and false if the request should succeed.
+func (e Errors) Pick() (int, bool) {
+	if e.Rate <= 0 || len(e.Codes) == 0 || rand.Float64() >= e.Rate {
+		return 0, false
+	}
+	return e.Codes[rand.IntN(len(e.Codes))], true
+}
+
+// Sleep blocks for d or until ctx is done, whichever happens first. It
+// returns ctx's error if the sleep was interrupted.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,417 @@
+package fault
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header is set on every response that carries an injected fault so
+// clients and proxies can tell synthetic failures from real ones.
+const Header = "X-Fault-Injected"
+
+// ErrDuplicateRuleID is returned when a rule is given the ID of an active
+// rule.
+var ErrDuplicateRuleID = errors.New("a fault rule with this id already exists")
+
+// maxRuleTTL bounds how long a rule may stay active, so a forgotten game
+// day rule cannot keep breaking a service indefinitely.
+const maxRuleTTL = 24 * time.Hour
+
+// Action names the fault a rule applies to matching requests.
+type Action string
+
+const (
+	// ActionDelay delays the request by Rule.Delay before handling it.
+	ActionDelay Action = "delay"
+
+	// ActionAbort responds immediately with Rule.Status without calling the handler.
+	ActionAbort Action = "abort"
+
+	// ActionPanic panics before calling the handler to exercise panic recovery.
+	ActionPanic Action = "panic"
+
+	// ActionHijack takes over the connection and closes it without a response.
+	ActionHijack Action = "hijack"
+
+	// ActionSlowBody trickles the response body out in small chunks,
+	// pausing Rule.Delay between chunks.
+	ActionSlowBody Action = "slow_body"
+)
+
+// Rule sources distinguish rules created through the admin API from rules
+// loaded from a file, so reloading the file leaves API rules untouched.
+const (
+	SourceAPI  = "api"
+	SourceFile = "file"
+)
+
+// Duration is a time.Duration that marshals to and from JSON as a string
+// such as "250ms".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"250ms\"")
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Rule applies a fault to requests matching a path pattern and method.
+//
+// Path uses path.Match syntax (e.g. "/stress/jobs/*"). A pattern ending in
+// "/**" additionally matches every path below its prefix. An empty Methods
+// list matches every method.
+//
+// Every rule expires. When creating a rule, set either TTL or ExpiresAt;
+// TTL is converted to an absolute ExpiresAt when the rule is added.
+type Rule struct {
+	ID          string    `json:"id"`
+	Path        string    `json:"path"`
+	Methods     []string  `json:"methods,omitempty"`
+	Action      Action    `json:"action"`
+	Probability float64   `json:"probability"`
+	Delay       Duration  `json:"delay,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	TTL         Duration  `json:"ttl,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Source      string    `json:"source"`
+}
+
+// Validate checks that the rule is complete and its values are in range.
+func (r Rule) Validate() error {
+	if err := r.validateFault(); err != nil {
+		return err
+	}
+	return r.validateExpiry(time.Now())
+}
+
+// validateFault checks the path, probability and action of the rule.
+func (r Rule) validateFault() error {
+	if r.Path == "" || !strings.HasPrefix(r.Path, "/") {
+		return errors.New("path must be a pattern starting with /")
+	}
+	if _, err := path.Match(r.Path, "/"); err != nil {
+		return fmt.Errorf("path is not a valid pattern: %w", err)
+	}
+	if r.Probability <= 0 || r.Probability > 1 {
+		return errors.New("probability must be greater than 0 and at most 1")
+	}
+
+	switch r.Action {
+	case ActionDelay, ActionSlowBody:
+		if r.Delay <= 0 || time.Duration(r.Delay) > 30*time.Second {
+			return fmt.Errorf("%s requires a delay between 0s and 30s", r.Action)
+		}
+	case ActionAbort:
+		if r.Status < 400 || r.Status > 599 {
+			return errors.New("abort requires a status between 400 and 599")
+		}
+	case ActionPanic, ActionHijack:
+	default:
+		return fmt.Errorf("action must be one of %s, %s, %s, %s, %s",
+			ActionDelay, ActionAbort, ActionPanic, ActionHijack, ActionSlowBody)
+	}
+	return nil
+}
+
+// validateExpiry checks that the rule expires within maxRuleTTL of now.
+func (r Rule) validateExpiry(now time.Time) error {
+	if r.TTL == 0 && r.ExpiresAt.IsZero() {
+		return errors.New("either ttl or expires_at is required")
+	}
+	if r.TTL < 0 || time.Duration(r.TTL) > maxRuleTTL {
+		return fmt.Errorf("ttl must be between 0s and %s", maxRuleTTL)
+	}
+	if r.TTL == 0 && !r.ExpiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+	if !r.ExpiresAt.IsZero() && r.ExpiresAt.Sub(now) > maxRuleTTL {
+		return fmt.Errorf("expires_at must be within %s", maxRuleTTL)
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to the method and path.
+func (r Rule) Matches(method, urlPath string) bool {
+	if len(r.Methods) > 0 && !slices.ContainsFunc(r.Methods, func(m string) bool {
+		return strings.EqualFold(m, method)
+	}) {
+		return false
+	}
+
+	if prefix, ok := strings.CutSuffix(r.Path, "/**"); ok {
+		return urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/")
+	}
+
+	matched, _ := path.Match(r.Path, urlPath)
+	return matched
+}
+
+// expired reports whether the rule is no longer active at now.
+func (r Rule) expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// rules is the registry of active fault rules, kept in creation order.
+//
+// fileRules holds the prepared rules of the last loaded file, including
+// expired ones, keyed by their definition in the file, so reloading the file
+// keeps the ID and expiry of rules that did not change.
+var rules = struct {
+	sync.RWMutex
+	list      []Rule
+	fileRules map[string][]Rule
+}{}
+
+// AddRule validates the rule, assigns it an ID unless it has one and an
+// expiry, and activates it. It returns the stored rule, or an error wrapping
+// ErrDuplicateRuleID if an active rule already has its ID.
+func AddRule(rule Rule) (Rule, error) {
+	now := time.Now()
+	rule, err := prepareRule(rule, SourceAPI, now)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	rules.Lock()
+	defer rules.Unlock()
+
+	pruneExpired(now)
+	if slices.ContainsFunc(rules.list, func(r Rule) bool { return r.ID == rule.ID }) {
+		return Rule{}, fmt.Errorf("%w: %s", ErrDuplicateRuleID, rule.ID)
+	}
+
+	rules.list = append(rules.list, rule)
+	return rule, nil
+}
+
+// RemoveRule deactivates the rule with the given ID. It reports whether the
+// rule existed.
+func RemoveRule(id string) bool {
+	rules.Lock()
+	defer rules.Unlock()
+
+	before := len(rules.list)
+	rules.list = slices.DeleteFunc(rules.list, func(r Rule) bool {
+		return r.ID == id
+	})
+	return len(rules.list) != before
+}
+
+// ClearRules deactivates every rule and returns how many were removed.
+func ClearRules() int {
+	rules.Lock()
+	defer rules.Unlock()
+
+	removed := len(rules.list)
+	rules.list = nil
+	rules.fileRules = nil
+	return removed
+}
+
+// ClearFileRules deactivates every rule loaded by LoadRulesFile and returns
+// how many were removed. Rules created through AddRule are kept.
+func ClearFileRules() int {
+	rules.Lock()
+	defer rules.Unlock()
+
+	before := len(rules.list)
+	rules.list = slices.DeleteFunc(rules.list, func(r Rule) bool {
+		return r.Source == SourceFile
+	})
+	rules.fileRules = nil
+	return before - len(rules.list)
+}
+
+// ListRules returns the active rules in creation order. Expired rules are
+// pruned as a side effect.
+func ListRules() []Rule {
+	rules.Lock()
+	defer rules.Unlock()
+
+	pruneExpired(time.Now())
+	return slices.Clone(rules.list)
+}
+
+// Match returns the first active rule matching the method and path whose
+// probability check passes, or false if no fault should be applied.
+func Match(method, urlPath string) (Rule, bool) {
+	now := time.Now()
+
+	rules.RLock()
+	defer rules.RUnlock()
+
+	for _, rule := range rules.list {
+		if rule.expired(now) || !rule.Matches(method, urlPath) {
+			continue
+		}
+		if rand.Float64() < rule.Probability {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// LoadResult describes the outcome of LoadRulesFile.
+type LoadResult struct {
+	// Loaded is the number of active rules loaded from the file.
+	Loaded int
+
+	// Expired identifies the rules of the file that were skipped because
+	// they have already expired, by ID or, for rules without one, by their
+	// index in the file.
+	Expired []string
+}
+
+// LoadRulesFile reads a JSON array of rules from filePath and replaces all
+// previously loaded file rules with them. Rules created through AddRule are
+// kept.
+//
+// TTLs in the file are relative to the time a rule is first loaded: a rule
+// whose definition is unchanged since the previous load keeps its ID and
+// expiry, so reloading the file does not extend it. Rules that have already
+// expired are skipped and reported in the result rather than failing the
+// load. A rule sharing its ID with another active rule fails the load with
+// an error wrapping ErrDuplicateRuleID. On error, the active rules are left
+// unchanged.
+func LoadRulesFile(filePath string) (LoadResult, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return LoadResult{}, err
+	}
+
+	var defined []Rule
+	if err := json.Unmarshal(data, &defined); err != nil {
+		return LoadResult{}, fmt.Errorf("parse fault rules file: %w", err)
+	}
+
+	rules.Lock()
+	defer rules.Unlock()
+
+	now := time.Now()
+	fileRules := make(map[string][]Rule, len(defined))
+	var (
+		loaded []Rule
+		result LoadResult
+	)
+	for i, rule := range defined {
+		definition, err := json.Marshal(rule)
+		if err != nil {
+			return LoadResult{}, fmt.Errorf("fault rule %d: %w", i, err)
+		}
+		key := string(definition)
+
+		// Identical definitions are matched to the previous rules in order
+		kept := rules.fileRules[key]
+		switch n := len(fileRules[key]); {
+		case n < len(kept):
+			rule = kept[n]
+		case rule.TTL == 0 && !rule.ExpiresAt.IsZero() && rule.expired(now):
+			if err := rule.validateFault(); err != nil {
+				return LoadResult{}, fmt.Errorf("fault rule %d: %w", i, err)
+			}
+		default:
+			if rule, err = prepareRule(rule, SourceFile, now); err != nil {
+				return LoadResult{}, fmt.Errorf("fault rule %d: %w", i, err)
+			}
+		}
+
+		if rule.expired(now) {
+			result.Expired = append(result.Expired, ruleName(i, defined[i]))
+		} else {
+			loaded = append(loaded, rule)
+		}
+		fileRules[key] = append(fileRules[key], rule)
+	}
+
+	pruneExpired(now)
+	list := slices.DeleteFunc(slices.Clone(rules.list), func(r Rule) bool {
+		return r.Source == SourceFile
+	})
+	list = append(list, loaded...)
+	if err := checkUniqueIDs(list); err != nil {
+		return LoadResult{}, err
+	}
+
+	rules.list = list
+	rules.fileRules = fileRules
+	result.Loaded = len(loaded)
+	return result, nil
+}
+
+// checkUniqueIDs returns an error wrapping ErrDuplicateRuleID if two of
+// list share an ID.
+func checkUniqueIDs(list []Rule) error {
+	seen := make(map[string]bool, len(list))
+	for _, rule := range list {
+		if seen[rule.ID] {
+			return fmt.Errorf("%w: %s", ErrDuplicateRuleID, rule.ID)
+		}
+		seen[rule.ID] = true
+	}
+	return nil
+}
+
+// ruleName identifies the rule at index i of a rules file in log messages.
+func ruleName(i int, rule Rule) string {
+	if rule.ID != "" {
+		return rule.ID
+	}
+	return fmt.Sprintf("#%d", i)
+}
+
+// prepareRule validates a rule and fills in its ID, source and expiry.
+func prepareRule(rule Rule, source string, now time.Time) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	if rule.ID == "" {
+		rule.ID = newRuleID()
+	}
+	if rule.TTL > 0 {
+		rule.ExpiresAt = now.Add(time.Duration(rule.TTL))
+	}
+	for i, method := range rule.Methods {
+		rule.Methods[i] = strings.ToUpper(method)
+	}
+	rule.Source = source
+	return rule, nil
+}
+
+// pruneExpired drops expired rules. The caller must hold the write lock.
+func pruneExpired(now time.Time) {
+	rules.list = slices.DeleteFunc(rules.list, func(r Rule) bool {
+		return r.expired(now)
+	})
+}
+
+// newRuleID returns a random 12 character hex identifier.
+func newRuleID() string {
+	b := make([]byte, 6)
+	_, _ = cryptorand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,259 @@
+package fault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		method  string
+		path    string
+		matches bool
+	}{
+		{name: "exact path", rule: Rule{Path: "/fault"}, method: "GET", path: "/fault", matches: true},
+		{name: "other path", rule: Rule{Path: "/fault"}, method: "GET", path: "/stress", matches: false},
+		{name: "wildcard segment", rule: Rule{Path: "/stress/jobs/*"}, method: "GET", path: "/stress/jobs/abc", matches: true},
+		{name: "wildcard stays in its segment", rule: Rule{Path: "/stress/*"}, method: "GET", path: "/stress/jobs/abc", matches: false},
+		{name: "subtree prefix itself", rule: Rule{Path: "/stress/**"}, method: "GET", path: "/stress", matches: true},
+		{name: "subtree descendant", rule: Rule{Path: "/stress/**"}, method: "GET", path: "/stress/jobs/abc", matches: true},
+		{name: "subtree sibling prefix", rule: Rule{Path: "/stress/**"}, method: "GET", path: "/stressful", matches: false},
+		{name: "method listed", rule: Rule{Path: "/fault", Methods: []string{"POST", "GET"}}, method: "GET", path: "/fault", matches: true},
+		{name: "method case", rule: Rule{Path: "/fault", Methods: []string{"get"}}, method: "GET", path: "/fault", matches: true},
+		{name: "method not listed", rule: Rule{Path: "/fault", Methods: []string{"POST"}}, method: "GET", path: "/fault", matches: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.method, tt.path); got != tt.matches {
+				t.Errorf("Matches(%s, %s) = %v, want %v", tt.method, tt.path, got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	valid := Rule{Path: "/fault", Action: ActionAbort, Status: 503, Probability: 1, TTL: Duration(time.Minute)}
+
+	tests := []struct {
+		name    string
+		modify  func(r *Rule)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*Rule) {}},
+		{name: "relative path", modify: func(r *Rule) { r.Path = "fault" }, wantErr: "path must be"},
+		{name: "bad pattern", modify: func(r *Rule) { r.Path = "/[" }, wantErr: "not a valid pattern"},
+		{name: "zero probability", modify: func(r *Rule) { r.Probability = 0 }, wantErr: "probability"},
+		{name: "abort without status", modify: func(r *Rule) { r.Status = 200 }, wantErr: "status between 400 and 599"},
+		{name: "delay without delay", modify: func(r *Rule) { r.Action = ActionDelay }, wantErr: "requires a delay"},
+		{name: "unknown action", modify: func(r *Rule) { r.Action = "explode" }, wantErr: "action must be one of"},
+		{name: "no expiry", modify: func(r *Rule) { r.TTL = 0 }, wantErr: "either ttl or expires_at"},
+		{name: "ttl too long", modify: func(r *Rule) { r.TTL = Duration(25 * time.Hour) }, wantErr: "ttl must be between"},
+		{
+			name:    "expires_at in the past",
+			modify:  func(r *Rule) { r.TTL, r.ExpiresAt = 0, time.Now().Add(-time.Second) },
+			wantErr: "must be in the future",
+		},
+		{
+			name:    "expires_at too far",
+			modify:  func(r *Rule) { r.TTL, r.ExpiresAt = 0, time.Now().Add(48*time.Hour) },
+			wantErr: "must be within",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.modify(&rule)
+
+			err := rule.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleExpiry(t *testing.T) {
+	t.Cleanup(func() { ClearRules() })
+
+	rule, err := AddRule(Rule{Path: "/fault", Action: ActionAbort, Status: 503, Probability: 1, TTL: Duration(time.Minute)})
+	if err != nil {
+		t.Fatalf("AddRule() error = %v", err)
+	}
+	if until := time.Until(rule.ExpiresAt); until <= 0 || until > time.Minute {
+		t.Errorf("ExpiresAt is %v from now, want within the 1m TTL", until)
+	}
+	if rule.ID == "" || rule.Source != SourceAPI {
+		t.Errorf("rule = %+v, want an ID and source %q", rule, SourceAPI)
+	}
+
+	if matched, ok := Match("GET", "/fault"); !ok || matched.ID != rule.ID {
+		t.Fatalf("Match() = %+v, %v; want the active rule", matched, ok)
+	}
+
+	// Expire the rule in place, as if its TTL had elapsed
+	rules.Lock()
+	rules.list[0].ExpiresAt = time.Now().Add(-time.Millisecond)
+	rules.Unlock()
+
+	if _, ok := Match("GET", "/fault"); ok {
+		t.Error("Match() returned an expired rule")
+	}
+	if list := ListRules(); len(list) != 0 {
+		t.Errorf("ListRules() = %+v, want expired rules pruned", list)
+	}
+}
+
+func TestLoadRulesFileKeepsAPIRules(t *testing.T) {
+	t.Cleanup(func() { ClearRules() })
+
+	apiRule, err := AddRule(Rule{Path: "/api", Action: ActionPanic, Probability: 1, TTL: Duration(time.Minute)})
+	if err != nil {
+		t.Fatalf("AddRule() error = %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "rules.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"path": "/a", "action": "delay", "delay": "10ms", "probability": 1, "ttl": "1h", "methods": ["get"]},
+	        {"path": "/b", "action": "abort", "status": 500, "probability": 0.5, "ttl": "1h"}]`)
+	if res, err := LoadRulesFile(file); err != nil || res.Loaded != 2 {
+		t.Fatalf("LoadRulesFile() = %+v, %v; want 2 rules", res, err)
+	}
+
+	write(`[{"path": "/c", "action": "abort", "status": 502, "probability": 1, "ttl": "1h"}]`)
+	if res, err := LoadRulesFile(file); err != nil || res.Loaded != 1 {
+		t.Fatalf("reloading LoadRulesFile() = %+v, %v; want 1 rule", res, err)
+	}
+
+	list := ListRules()
+	if len(list) != 2 || list[0].ID != apiRule.ID || list[1].Path != "/c" || list[1].Source != SourceFile {
+		t.Fatalf("ListRules() = %+v, want the API rule and the reloaded file rule", list)
+	}
+
+	// An invalid file leaves the active rules unchanged
+	write(`[{"path": "/d", "action": "abort", "status": 200, "probability": 1, "ttl": "1h"}]`)
+	if _, err := LoadRulesFile(file); err == nil {
+		t.Fatal("LoadRulesFile() accepted an invalid rule")
+	}
+	if list := ListRules(); len(list) != 2 {
+		t.Errorf("ListRules() after a failed load = %+v, want the previous rules", list)
+	}
+
+	if removed := ClearFileRules(); removed != 1 {
+		t.Errorf("ClearFileRules() = %d, want 1", removed)
+	}
+	if !RemoveRule(apiRule.ID) || RemoveRule(apiRule.ID) {
+		t.Error("RemoveRule() did not report the removal exactly once")
+	}
+}
+
+func TestLoadRulesFileExpiry(t *testing.T) {
+	t.Cleanup(func() { ClearRules() })
+
+	file := filepath.Join(t.TempDir(), "rules.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	write(`[{"path": "/ttl", "action": "panic", "probability": 1, "ttl": "1h"},
+	        {"id": "game-day", "path": "/old", "action": "panic", "probability": 1, "expires_at": "` + past + `"},
+	        {"path": "/older", "action": "panic", "probability": 1, "expires_at": "` + past + `"}]`)
+
+	res, err := LoadRulesFile(file)
+	if err != nil {
+		t.Fatalf("LoadRulesFile() with expired rules = %v, want them skipped", err)
+	}
+	if res.Loaded != 1 || !slices.Equal(res.Expired, []string{"game-day", "#2"}) {
+		t.Fatalf("LoadRulesFile() = %+v, want 1 rule and game-day, #2 expired", res)
+	}
+	first := ListRules()[0]
+
+	// Reloading an unchanged rule keeps its ID and expiry
+	if _, err := LoadRulesFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if again := ListRules()[0]; again.ID != first.ID || !again.ExpiresAt.Equal(first.ExpiresAt) {
+		t.Errorf("reloaded rule = %+v, want the ID and expiry of %+v", again, first)
+	}
+
+	// Once its TTL elapses, reloading does not revive the rule
+	rules.Lock()
+	for _, kept := range rules.fileRules {
+		kept[0].ExpiresAt = time.Now().Add(-time.Millisecond)
+	}
+	rules.Unlock()
+
+	res, err = LoadRulesFile(file)
+	if err != nil || res.Loaded != 0 || len(res.Expired) != 3 {
+		t.Errorf("LoadRulesFile() after the TTL = %+v, %v; want every rule expired", res, err)
+	}
+
+	// Changing a rule restarts its TTL
+	write(`[{"path": "/ttl", "action": "panic", "probability": 0.5, "ttl": "1h"}]`)
+	if res, err := LoadRulesFile(file); err != nil || res.Loaded != 1 {
+		t.Errorf("LoadRulesFile() of a changed rule = %+v, %v; want it loaded", res, err)
+	}
+}
+
+func TestDuplicateRuleIDs(t *testing.T) {
+	t.Cleanup(func() { ClearRules() })
+
+	rule := Rule{ID: "game-day", Path: "/fault", Action: ActionPanic, Probability: 1, TTL: Duration(time.Minute)}
+	if _, err := AddRule(rule); err != nil {
+		t.Fatalf("AddRule() error = %v", err)
+	}
+	if _, err := AddRule(rule); !errors.Is(err, ErrDuplicateRuleID) {
+		t.Errorf("AddRule() of a duplicate ID = %v, want ErrDuplicateRuleID", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "rules.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A file rule may not take the ID of an API rule
+	write(`[{"id": "game-day", "path": "/a", "action": "panic", "probability": 1, "ttl": "1h"}]`)
+	if _, err := LoadRulesFile(file); !errors.Is(err, ErrDuplicateRuleID) {
+		t.Errorf("LoadRulesFile() colliding with an API rule = %v, want ErrDuplicateRuleID", err)
+	}
+
+	// Nor may two rules in the file share one
+	write(`[{"id": "twice", "path": "/a", "action": "panic", "probability": 1, "ttl": "1h"},
+	        {"id": "twice", "path": "/b", "action": "panic", "probability": 1, "ttl": "1h"}]`)
+	if _, err := LoadRulesFile(file); !errors.Is(err, ErrDuplicateRuleID) {
+		t.Errorf("LoadRulesFile() with a repeated ID = %v, want ErrDuplicateRuleID", err)
+	}
+	if list := ListRules(); len(list) != 1 {
+		t.Errorf("ListRules() after failed loads = %+v, want only the API rule", list)
+	}
+
+	// Once the rule is removed, its ID is free again
+	RemoveRule("game-day")
+	if _, err := AddRule(rule); err != nil {
+		t.Errorf("AddRule() after removal = %v, want nil", err)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/config"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// ConfigHandler returns the effective configuration after all sources have
+// been merged. Secret values are redacted.
+//
+// Endpoint: GET /admin/config
+// Response: JSON object keyed by config file keys.
+func ConfigHandler(w http.ResponseWriter, r *http.Request) {
+	response.Send(w, r, http.StatusOK, config.Redacted(config.Current()))
+}
+
+// LogLevelRequest is the request body for changing the log level.
+type LogLevelRequest struct {
+	// Level is the new base level. Empty keeps the current base level.
+	Level string `json:"level"`
+
+	// Components maps component names, such as "handlers" or "middleware",
+	// to levels that apply to that component only.
+	Components map[string]string `json:"components"`
+
+	// TTL, when set, reverts the change after this duration (e.g. "15m").
+	TTL string `json:"ttl"`
+}
+
+// GetLogLevelHandler returns the effective log level configuration.
+//
+// Endpoint: GET /admin/loglevel
+// Response: JSON logger.Levels.
+func GetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	response.Send(w, r, http.StatusOK, logger.CurrentLevels())
+}
+
+// SetLogLevelHandler overrides the log level at runtime, for example to
+// switch a single pod to debug during an incident without restarting it.
+// The override replaces any previous one and takes precedence over the
+// configured level until it expires or is cleared.
+//
+// Endpoint: PUT /admin/loglevel
+//
+// Request body: JSON LogLevelRequest, for example:
+//
+//	{"level": "info", "components": {"handlers": "debug"}, "ttl": "15m"}
+//
+// Response: JSON logger.Levels, or 400 if a level or the TTL is invalid.
+func SetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRuleBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		response.SendError(w, r, http.StatusBadRequest, "invalid log level request: "+err.Error())
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			response.SendError(w, r, http.StatusBadRequest, "invalid log level request: ttl must be a positive duration such as 15m")
+			return
+		}
+	}
+
+	previous := logger.CurrentLevels()
+
+	levels, err := logger.SetOverride(req.Level, req.Components, ttl)
+	if err != nil {
+		response.SendError(w, r, http.StatusBadRequest, "invalid log level request: "+err.Error())
+		return
+	}
+
+	logger.AuditContext(r.Context()).
+		Str("previous_log_level", previous.Level).
+		Interface("previous_components", previous.Components).
+		Str("log_level", levels.Level).
+		Interface("components", levels.Components).
+		Dur("ttl", ttl).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Log level changed")
+
+	response.Send(w, r, http.StatusOK, levels)
+}
+
+// ResetLogLevelHandler removes the runtime override so the configured log
+// level applies again.
+//
+// Endpoint: DELETE /admin/loglevel
+// Response: JSON logger.Levels.
+func ResetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	previous := logger.CurrentLevels()
+
+	if logger.ClearOverride() {
+		logger.AuditContext(r.Context()).
+			Str("previous_log_level", previous.Level).
+			Interface("previous_components", previous.Components).
+			Str("log_level", previous.Configured).
+			Str("remote_addr", r.RemoteAddr).
+			Msg("Log level reset to configured level")
+	}
+
+	response.Send(w, r, http.StatusOK, logger.CurrentLevels())
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/moabdelazem/go-gitops-app/internal/fault"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// maxRuleBodyBytes limits the size of a fault rule request body.
+const maxRuleBodyBytes = 64 << 10
+
+// FaultRuleList is the response body for listing fault rules.
+type FaultRuleList struct {
+	Count int          `json:"count"`
+	Rules []fault.Rule `json:"rules"`
+}
+
+// ListFaultRulesHandler lists the active chaos fault rules.
+//
+// Endpoint: GET /admin/faults
+// Response: JSON with the rule count and every active rule.
+func ListFaultRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules := fault.ListRules()
+	if rules == nil {
+		rules = []fault.Rule{}
+	}
+
+	response.Send(w, r, http.StatusOK, FaultRuleList{Count: len(rules), Rules: rules})
+}
+
+// CreateFaultRuleHandler activates a new chaos fault rule.
+//
+// Endpoint: POST /admin/faults
+//
+// Request body: JSON fault.Rule, for example:
+//
+//	{"path": "/stress", "methods": ["GET"], "action": "delay", "delay": "2s", "probability": 0.5, "ttl": "10m"}
+//
+// Response: 201 Created with the stored rule, 400 if the rule is invalid, or
+// 409 if an active rule already has its id.
+func CreateFaultRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule fault.Rule
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRuleBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rule); err != nil {
+		response.SendError(w, r, http.StatusBadRequest, "invalid fault rule: "+err.Error())
+		return
+	}
+
+	rule, err := fault.AddRule(rule)
+	if errors.Is(err, fault.ErrDuplicateRuleID) {
+		response.SendError(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		response.SendError(w, r, http.StatusBadRequest, "invalid fault rule: "+err.Error())
+		return
+	}
+
+	logger.FromContext(r.Context()).Warn().
+		Str("rule_id", rule.ID).
+		Str("rule_path", rule.Path).
+		Strs("methods", rule.Methods).
+		Str("action", string(rule.Action)).
+		Float64("probability", rule.Probability).
+		Time("expires_at", rule.ExpiresAt).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Fault rule created")
+
+	w.Header().Set("Location", "/admin/faults/"+rule.ID)
+	response.Send(w, r, http.StatusCreated, rule)
+}
+
+// DeleteFaultRuleHandler deactivates a single chaos fault rule.
+//
+// Endpoint: DELETE /admin/faults/{id}
+// Response: JSON confirmation, or 404 if the rule does not exist.
+func DeleteFaultRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if !fault.RemoveRule(id) {
+		response.SendError(w, r, http.StatusNotFound, "fault rule not found")
+		return
+	}
+
+	logger.FromContext(r.Context()).Warn().
+		Str("rule_id", id).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Fault rule deleted")
+
+	response.Send(w, r, http.StatusOK, response.Success("fault rule deleted"))
+}
+
+// ClearFaultRulesHandler deactivates every chaos fault rule, including rules
+// loaded from the rules file.
+//
+// Endpoint: DELETE /admin/faults
+// Response: JSON confirmation.
+func ClearFaultRulesHandler(w http.ResponseWriter, r *http.Request) {
+	removed := fault.ClearRules()
+
+	logger.FromContext(r.Context()).Warn().
+		Int("removed", removed).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("All fault rules cleared")
+
+	response.Send(w, r, http.StatusOK, response.Success("all fault rules cleared"))
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/internal/fault"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// FaultRequest represents the validated parameters for a fault injection
+// request. parseAndValidateFaultRequest ensures all values are within
+// acceptable bounds.
+type FaultRequest struct {
+	// Latency is the delay distribution (fixed, uniform, normal, longtail).
+	// Empty means no delay is injected.
+	Latency string
+
+	// Delay is the fixed delay, the normal mean, or the longtail base delay (0-30s).
+	Delay time.Duration
+
+	// Min and Max bound the uniform distribution (0-30s).
+	Min time.Duration
+	Max time.Duration
+
+	// StdDev is the standard deviation of the normal distribution (0-30s).
+	StdDev time.Duration
+
+	// Tail is the delay applied beyond Percentile in the longtail distribution (0-30s).
+	Tail time.Duration
+
+	// Percentile is the share of longtail requests that get the base delay (0-100).
+	Percentile float64
+
+	// ErrorRate is the probability of returning an error (0-1).
+	ErrorRate float64
+
+	// ErrorCodes are the status codes to choose from when failing (400-599).
+	ErrorCodes []int
+}
+
+// FaultResponse represents the response from a fault injection request that
+// did not fail.
+type FaultResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Latency string `json:"latency,omitempty"`
+	Delay   string `json:"delay"`
+}
+
+// FaultHandler injects synthetic latency and errors to exercise latency- and
+// error-rate-based alerting built on http_request_duration_seconds and
+// http_requests_total.
+//
+// Endpoint: GET /fault
+//
+// Query Parameters:
+//   - latency: Delay distribution - fixed, uniform, normal, longtail. Default: none
+//   - delay: Fixed delay, normal mean, or longtail base delay (e.g., "200ms")
+//   - min, max: Bounds of the uniform distribution
+//   - stddev: Standard deviation of the normal distribution
+//   - tail, percentile: Longtail delay and the percentile it starts at (e.g., tail=2s&percentile=99)
+//   - error_rate: Probability of failing the request (0-1). Default: 0
+//   - error_codes: Comma-separated status codes to fail with. Default: 500
+//
+// Examples:
+//   - GET /fault?latency=fixed&delay=300ms
+//   - GET /fault?latency=uniform&min=50ms&max=500ms
+//   - GET /fault?latency=normal&delay=200ms&stddev=50ms
+//   - GET /fault?latency=longtail&delay=20ms&tail=2s&percentile=99
+//   - GET /fault?error_rate=0.1&error_codes=500,503
+//
+// Every injected fault is logged, counted in injected_faults_total and
+// flagged with the X-Fault-Injected response header.
+//
+// ! WARNING: This endpoint is intended for testing purposes only.
+func (a *App) FaultHandler(w http.ResponseWriter, r *http.Request) {
+	req, latency, err := parseAndValidateFaultRequest(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn().
+			Err(err).
+			Str("path", r.URL.Path).
+			Msg("Invalid fault request parameters")
+
+		response.SendError(w, r, http.StatusBadRequest, err.Error(), fieldErrors(err)...)
+		return
+	}
+
+	var injected []string
+
+	delay := latency.Sample()
+	if delay > 0 {
+		if err := fault.Sleep(r.Context(), delay); err != nil {
+			// The client is gone, but the request is still logged and
+			// counted, as 499 like other requests the client gave up on
+			logger.FromContext(r.Context()).Info().
+				Str("path", r.URL.Path).
+				Dur("delay", delay).
+				Msg("Client disconnected during injected latency")
+
+			response.SendError(w, r, response.StatusClientClosedRequest, "client closed request during injected latency")
+			return
+		}
+
+		injected = append(injected, "latency")
+		a.rec.TrackInjectedFault("latency", "")
+
+		logger.FromContext(r.Context()).Info().
+			Str("path", r.URL.Path).
+			Str("distribution", req.Latency).
+			Dur("delay", delay).
+			Msg("Injected latency fault")
+	}
+
+	errs := fault.Errors{Rate: req.ErrorRate, Codes: req.ErrorCodes}
+	if code, ok := errs.Pick(); ok {
+		injected = append(injected, "error")
+		a.rec.TrackInjectedFault("error", strconv.Itoa(code))
+
+		logger.FromContext(r.Context()).Info().
+			Str("path", r.URL.Path).
+			Int("status", code).
+			Float64("error_rate", req.ErrorRate).
+			Msg("Injected error fault")
+
+		w.Header().Set(fault.Header, strings.Join(injected, ","))
+		response.SendError(w, r, code, "injected fault: "+http.StatusText(code))
+		return
+	}
+
+	if len(injected) > 0 {
+		w.Header().Set(fault.Header, strings.Join(injected, ","))
+	}
+
+	response.Send(w, r, http.StatusOK, FaultResponse{
+		Status:  "success",
+		Message: "Request completed",
+		Latency: req.Latency,
+		Delay:   delay.String(),
+	})
+}
+
+// maxFaultDelay bounds every delay parameter of a fault request.
+const maxFaultDelay = 30 * time.Second
+
+// parseAndValidateFaultRequest extracts and validates fault injection
+// parameters from the HTTP request query string.
+//
+// Returns a validated FaultRequest with the latency distribution it
+// describes, or ValidationErrors listing every malformed or out-of-range
+// parameter. Parameters are checked in a fixed order, so the same request
+// always reports the same errors.
+func parseAndValidateFaultRequest(r *http.Request) (*FaultRequest, fault.Latency, error) {
+	query := r.URL.Query()
+
+	req := &FaultRequest{
+		ErrorCodes: []int{http.StatusInternalServerError},
+	}
+
+	var errs ValidationErrors
+
+	if value := query.Get("latency"); value != "" {
+		switch fault.Distribution(value) {
+		case fault.Fixed, fault.Uniform, fault.Normal, fault.LongTail:
+			req.Latency = value
+		default:
+			errs = append(errs, &ValidationError{
+				Field:   "latency",
+				Message: "latency must be one of fixed, uniform, normal, longtail",
+				Value:   value,
+			})
+		}
+	}
+
+	durations := []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"delay", &req.Delay},
+		{"min", &req.Min},
+		{"max", &req.Max},
+		{"stddev", &req.StdDev},
+		{"tail", &req.Tail},
+	}
+	for _, d := range durations {
+		value := query.Get(d.name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		switch {
+		case err != nil:
+			errs = append(errs, faultRangeError(d.name, value, d.name+" must be a duration such as 200ms", "0s", maxFaultDelay.String()))
+		case parsed < 0 || parsed > maxFaultDelay:
+			errs = append(errs, faultRangeError(d.name, value, "", "0s", maxFaultDelay.String()))
+		default:
+			*d.target = parsed
+		}
+	}
+
+	floats := []struct {
+		name         string
+		target       *float64
+		lower, upper float64
+	}{
+		{"percentile", &req.Percentile, 0, 100},
+		{"error_rate", &req.ErrorRate, 0, 1},
+	}
+	for _, f := range floats {
+		value := query.Get(f.name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(value, 64)
+		switch {
+		case err != nil:
+			errs = append(errs, faultRangeError(f.name, value, f.name+" must be a number", f.lower, f.upper))
+		case !(parsed >= f.lower && parsed <= f.upper):
+			// Written to reject NaN as well
+			errs = append(errs, faultRangeError(f.name, value, "", f.lower, f.upper))
+		default:
+			*f.target = parsed
+		}
+	}
+
+	if value := query.Get("error_codes"); value != "" {
+		codes, err := parseErrorCodes(value)
+		switch {
+		case err != nil:
+			errs = append(errs, faultRangeError("error_codes", value, "error_codes must be a comma-separated list of status codes", 400, 599))
+		case slices.ContainsFunc(codes, func(code int) bool { return code < 400 || code > 599 }):
+			errs = append(errs, faultRangeError("error_codes", value, "error_codes must contain status codes between 400 and 599", 400, 599))
+		default:
+			req.ErrorCodes = codes
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fault.Latency{}, errs
+	}
+
+	latency := fault.Latency{
+		Distribution: fault.Distribution(req.Latency),
+		Delay:        req.Delay,
+		Min:          req.Min,
+		Max:          req.Max,
+		StdDev:       req.StdDev,
+		Tail:         req.Tail,
+		Percentile:   req.Percentile,
+	}
+	if err := latency.Validate(); err != nil {
+		return nil, fault.Latency{}, ValidationErrors{{Field: "latency", Message: err.Error(), Value: req.Latency}}
+	}
+
+	return req, latency, nil
+}
+
+// parseErrorCodes parses a comma-separated list of status codes.
+func parseErrorCodes(value string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(value, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// faultRangeError returns the error for an invalid fault parameter bounded
+// by lower and upper, with message or, if empty, the allowed range as its
+// message.
+func faultRangeError(field, value, message string, lower, upper any) *ValidationError {
+	if message == "" {
+		message = fmt.Sprintf("%s must be between %v and %v", field, lower, upper)
+	}
+	return &ValidationError{
+		Field:   field,
+		Message: message,
+		Value:   value,
+		Min:     lower,
+		Max:     upper,
+	}
+}
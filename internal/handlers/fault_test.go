@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+func TestParseAndValidateFaultRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantFields []string
+	}{
+		{name: "no faults", query: ""},
+		{name: "fixed latency", query: "latency=fixed&delay=300ms"},
+		{name: "errors", query: "error_rate=0.1&error_codes=500,%20503"},
+		{
+			name:       "every field is reported in a fixed order",
+			query:      "error_codes=200&error_rate=2&percentile=x&tail=1m&max=soon&delay=-1s&latency=weird",
+			wantFields: []string{"latency", "delay", "max", "tail", "percentile", "error_rate", "error_codes"},
+		},
+		{name: "NaN is out of range", query: "error_rate=NaN", wantFields: []string{"error_rate"}},
+		{name: "malformed error codes", query: "error_codes=500,oops", wantFields: []string{"error_codes"}},
+		{name: "inconsistent distribution", query: "latency=uniform&min=2s&max=1s", wantFields: []string{"latency"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseAndValidateFaultRequest(httptest.NewRequest(http.MethodGet, "/fault?"+tt.query, nil))
+
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if got := fields(t, err); !slices.Equal(got, tt.wantFields) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestFaultValidationErrorsReportBounds(t *testing.T) {
+	_, _, err := parseAndValidateFaultRequest(httptest.NewRequest(http.MethodGet, "/fault?delay=1m&error_rate=1.5", nil))
+
+	got := fieldErrors(err)
+	if len(got) != 2 {
+		t.Fatalf("fieldErrors() = %+v, want 2 errors", got)
+	}
+	if got[0].Message != "delay must be between 0s and 30s" || got[0].Value != "1m" || got[0].Max != "30s" {
+		t.Errorf("delay field error = %+v", got[0])
+	}
+	if got[1].Message != "error_rate must be between 0 and 1" || got[1].Value != "1.5" || got[1].Max != 1.0 {
+		t.Errorf("error_rate field error = %+v", got[1])
+	}
+}
+
+func TestFaultHandlerClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/fault?latency=fixed&delay=10s", nil)
+	New(metrics.Nop()).FaultHandler(rec, req)
+
+	if rec.Code != response.StatusClientClosedRequest {
+		t.Errorf("status = %d, want %d", rec.Code, response.StatusClientClosedRequest)
+	}
+}
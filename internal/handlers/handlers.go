@@ -2,70 +2,126 @@
 //
 // This package contains all the HTTP handler functions that process incoming
 // requests. Each handler is responsible for a specific endpoint and follows
-// a consistent pattern of logging, metric tracking, and response formatting.
+// a consistent pattern of logging and response formatting. Request metrics
+// are recorded centrally by middleware.Logging.
 //
 // The handlers in this package are designed to work with Gorilla Mux router
 // and utilize structured logging for observability.
 package handlers
 
 import (
+	"context"
+	"errors"
 	"math"
 	"net/http"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/config"
 	"github.com/moabdelazem/go-gitops-app/pkg/logger"
 	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
 	"github.com/moabdelazem/go-gitops-app/pkg/response"
+	"github.com/moabdelazem/go-gitops-app/pkg/tracing"
+	"github.com/moabdelazem/go-gitops-app/pkg/version"
 )
 
 const (
-	// AppVersion represents the current version of the application.
-	// This is included in API responses for client version tracking.
-	AppVersion = "v1.0.0"
+	// Stress run outcomes reported in StressResponse and metrics
+	outcomeCompleted = "completed"
+	outcomeCancelled = "cancelled"
 
-	// Default stress test configuration
-	defaultStressDuration = 2 * time.Second
-	maxStressDuration     = 30 * time.Second
+	// Routes that start CPU stress workers, used as the saturation metric label
+	routeStress     = "/stress"
+	routeStressJobs = "/stress/jobs"
 )
 
-// validate is the singleton validator instance used across all handlers.
-var validate = validator.New()
+// minStressDuration is the shortest CPU stress run a request may ask for.
+const minStressDuration = 100 * time.Millisecond
+
+// errServerShutdown is the cancellation cause for stress runs stopped by
+// CancelStressRuns during server shutdown.
+var errServerShutdown = errors.New("server shutting down")
+
+// App holds the state of the handlers that run stress tests or record
+// metrics: the metrics recorder, the running stress tests and workers, and
+// the background stress jobs. Two servers in one process keep separate
+// metrics, stress runs and jobs. Health probe state is process-wide instead
+// (see RegisterHealthChecks), since Kubernetes probes the process as a whole.
+//
+// Handlers without such state, such as HomeHandler, are plain functions.
+type App struct {
+	// rec receives the metrics recorded by the handlers.
+	rec metrics.Recorder
+
+	// stressCtx is the shared shutdown context for all stress runs. It is
+	// cancelled by CancelStressRuns during server shutdown so that running
+	// workers stop early instead of being killed when the process exits.
+	stressCtx    context.Context
+	cancelStress context.CancelCauseFunc
+
+	// activeStressRuns counts the stress tests that are currently executing.
+	activeStressRuns atomic.Int64
+
+	// activeStressWorkers counts the stress worker goroutines currently
+	// running across all stress tests. It drives the saturation readiness
+	// check.
+	activeStressWorkers atomic.Int64
+
+	// jobs is the registry of background stress jobs.
+	jobs jobRegistry
+}
+
+// New creates an App recording its metrics in rec, such as stress run
+// outcomes and injected faults. Use metrics.Nop to discard them.
+func New(rec metrics.Recorder) *App {
+	stressCtx, cancelStress := context.WithCancelCause(context.Background())
+	return &App{
+		rec:          rec,
+		stressCtx:    stressCtx,
+		cancelStress: cancelStress,
+		jobs:         jobRegistry{byID: make(map[string]*stressJob)},
+	}
+}
 
 // StressRequest represents the validated parameters for a stress test.
-// Validation tags ensure all values are within acceptable bounds.
 type StressRequest struct {
-	// DurationSeconds is the stress test duration in seconds (1-30).
-	DurationSeconds int `validate:"min=1,max=30"`
+	// Duration is the stress test duration (minStressDuration to the
+	// configured maximum).
+	Duration time.Duration
 
 	// Workers is the number of concurrent CPU workers (1 to 2x CPU cores).
-	Workers int `validate:"min=1"`
+	Workers int
 }
 
 // StressResponse represents the response from a stress test.
+// CPU runs report Workers; memory runs report AllocatedMiB.
 type StressResponse struct {
-	Status   string `json:"status"`
-	Message  string `json:"message"`
-	Duration string `json:"duration"`
-	Workers  int    `json:"workers"`
+	Status       string `json:"status"`
+	Message      string `json:"message"`
+	Outcome      string `json:"outcome"`
+	Duration     string `json:"duration"`
+	Workers      int    `json:"workers,omitempty"`
+	AllocatedMiB int    `json:"allocated_mib,omitempty"`
 }
 
 // HomeHandler handles requests to the root endpoint.
-// It returns a welcome message along with the current application version.
+// It returns a welcome message along with the build version of the binary.
 //
 // Endpoint: GET /
-// Response: JSON with status, message, and version fields.
+// Response: JSON with status, message, and version fields, with an ETag for
+// conditional requests.
 //
-// This handler tracks the request in Prometheus metrics and logs the
-// request at debug level.
+// This handler logs the request at debug level.
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
-	// Track request metrics for monitoring
-	metrics.TrackRequest(r.URL.Path, r.Method)
-
-	logger.Debug().
+	logger.FromContext(r.Context()).Debug().
 		Str("path", r.URL.Path).
 		Str("method", r.Method).
 		Str("remote_addr", r.RemoteAddr).
@@ -74,32 +130,38 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	resp := response.New(
 		"success",
 		"Welcome to the Resilient GitOps Platform!",
-		AppVersion,
+		version.Get().Version,
 	)
 
-	response.SendJSON(w, http.StatusOK, resp)
+	response.SendCacheable(w, r, resp)
 }
 
-// HealthHandler handles health check requests for Kubernetes probes.
-// It returns a simple "OK" response to indicate the service is healthy.
-//
-// Endpoint: GET /health
-// Response: Plain text "OK" with 200 status code.
-//
-// This handler intentionally does not track metrics to avoid noise from
-// frequent health check requests by Kubernetes liveness/readiness probes.
-//
-// Usage:
-//   - Kubernetes Liveness Probe: Ensures the container is running
-//   - Kubernetes Readiness Probe: Ensures the service is ready to accept traffic
-func HealthHandler(w http.ResponseWriter, r *http.Request) {
-	// Health checks are not logged at info level to reduce noise
-	logger.Debug().
-		Str("path", r.URL.Path).
-		Msg("Health check requested")
+// beginStressRun records the start of a stress run in the active run count
+// and the stress queue depth scaling signal. It must be paired with
+// endStressRun.
+func (a *App) beginStressRun() {
+	a.activeStressRuns.Add(1)
+	a.rec.AddStressQueueDepth(1)
+}
+
+// endStressRun records the end of a stress run started with beginStressRun.
+func (a *App) endStressRun() {
+	a.activeStressRuns.Add(-1)
+	a.rec.AddStressQueueDepth(-1)
+}
+
+// ActiveStressRuns returns the number of stress tests currently executing.
+func (a *App) ActiveStressRuns() int {
+	return int(a.activeStressRuns.Load())
+}
 
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("OK"))
+// CancelStressRuns signals all running stress workers to stop and returns the
+// number of stress tests that were active at the time of cancellation.
+// Stress tests started after this call finish immediately.
+func (a *App) CancelStressRuns() int {
+	active := a.ActiveStressRuns()
+	a.cancelStress(errServerShutdown)
+	return active
 }
 
 // StressHandler simulates high CPU load to trigger Horizontal Pod Autoscaler (HPA).
@@ -109,8 +171,12 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 // Endpoint: GET /stress
 //
 // Query Parameters:
-//   - duration: How long to run the stress test (e.g., "5s", "10s"). Default: 2s, Max: 30s
-//   - workers: Number of concurrent CPU workers. Default: number of CPU cores
+//   - duration: How long to run the stress test (e.g., "500ms", "10s"). Default: 2s,
+//     Min: 100ms, Max: 30s (default and max configurable)
+//   - workers: Number of concurrent CPU workers. Default: number of CPU cores,
+//     Max: 2x CPU cores (larger values are capped)
+//
+// Malformed or out-of-range parameters are all reported in one 400 response.
 //
 // Examples:
 //   - GET /stress                     (2s duration, all cores)
@@ -118,29 +184,30 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 //   - GET /stress?workers=2           (2s duration, 2 cores)
 //   - GET /stress?duration=10s&workers=4
 //
-// Response: JSON with status, duration, and worker count.
+// The run stops early if the client disconnects or the server shuts down.
+//
+// Response: JSON with status, outcome, duration, and worker count. Completed
+// runs return 200. Cancelled runs report outcome "cancelled" with 503 during
+// shutdown, or 499 if the client disconnected.
 //
 // ! WARNING: This endpoint is intended for testing purposes and the nature of this experimental api
 // ! Real applications does not have something like this
-func StressHandler(w http.ResponseWriter, r *http.Request) {
-	// Track request metrics for monitoring
-	metrics.TrackRequest(r.URL.Path, r.Method)
-
+func (a *App) StressHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse and validate request parameters
 	req, err := parseAndValidateStressRequest(r)
 	if err != nil {
-		logger.Warn().
+		logger.FromContext(r.Context()).Warn().
 			Err(err).
 			Str("path", r.URL.Path).
 			Msg("Invalid stress request parameters")
 
-		response.SendJSON(w, http.StatusBadRequest, response.Error(err.Error()))
+		response.SendError(w, r, http.StatusBadRequest, err.Error(), fieldErrors(err)...)
 		return
 	}
 
-	duration := time.Duration(req.DurationSeconds) * time.Second
+	duration := req.Duration
 
-	logger.Warn().
+	logger.FromContext(r.Context()).Warn().
 		Str("path", r.URL.Path).
 		Str("remote_addr", r.RemoteAddr).
 		Dur("duration", duration).
@@ -148,11 +215,37 @@ func StressHandler(w http.ResponseWriter, r *http.Request) {
 		Msg("Multi-core stress test initiated - CPU spike incoming")
 
 	// Execute stress test across multiple goroutines
+	a.beginStressRun()
+	defer a.endStressRun()
+
+	ctx, cancel := a.stressContext(r.Context())
+	defer cancel()
+
 	start := time.Now()
-	runMultiCoreStress(duration, req.Workers)
+	outcome := a.runMultiCoreStress(ctx, routeStress, duration, req.Workers)
 	elapsed := time.Since(start)
 
-	logger.Info().
+	a.rec.TrackStressRun("cpu", outcome)
+
+	if outcome == outcomeCancelled {
+		statusCode := cancelledStatus(ctx)
+		cancelledLogEvent(r.Context(), statusCode).
+			Dur("duration", elapsed).
+			Int("workers", req.Workers).
+			AnErr("reason", context.Cause(ctx)).
+			Msg("Stress test cancelled")
+
+		response.Send(w, r, statusCode, StressResponse{
+			Status:   "stress_cancelled",
+			Message:  "CPU load simulation cancelled before completion",
+			Outcome:  outcome,
+			Duration: elapsed.String(),
+			Workers:  req.Workers,
+		})
+		return
+	}
+
+	logger.FromContext(r.Context()).Info().
 		Dur("duration", elapsed).
 		Int("workers", req.Workers).
 		Msg("Stress test completed")
@@ -161,86 +254,143 @@ func StressHandler(w http.ResponseWriter, r *http.Request) {
 	resp := StressResponse{
 		Status:   "stress_complete",
 		Message:  "CPU load simulation finished",
+		Outcome:  outcome,
 		Duration: elapsed.String(),
 		Workers:  req.Workers,
 	}
 
-	response.SendJSON(w, http.StatusOK, resp)
+	response.Send(w, r, http.StatusOK, resp)
+}
+
+// cancelledStatus returns the response status for a request whose stress run
+// was cancelled through ctx (see stressContext): 503 if the server is
+// shutting down, or response.StatusClientClosedRequest if the client
+// disconnected, which is not a server error.
+func cancelledStatus(ctx context.Context) int {
+	if errors.Is(context.Cause(ctx), errServerShutdown) {
+		return http.StatusServiceUnavailable
+	}
+	return response.StatusClientClosedRequest
+}
+
+// cancelledLogEvent returns the event logging a stress run cancelled with
+// statusCode (see cancelledStatus): a warning for shutdown, and info for a
+// client that gave up.
+func cancelledLogEvent(ctx context.Context, statusCode int) *zerolog.Event {
+	if statusCode == response.StatusClientClosedRequest {
+		return logger.FromContext(ctx).Info()
+	}
+	return logger.FromContext(ctx).Warn()
+}
+
+// stressContext derives the context for a single stress run from parent.
+// The returned context is cancelled when parent is done (for example when
+// the client disconnects) or when CancelStressRuns is called during shutdown.
+// context.Cause on the returned context reports which of the two happened.
+func (a *App) stressContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := context.AfterFunc(a.stressCtx, func() {
+		cancel(context.Cause(a.stressCtx))
+	})
+
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
 }
 
 // parseAndValidateStressRequest extracts and validates stress test parameters
-// from the HTTP request query string using go-playground/validator.
+// from the HTTP request query string.
 //
-// Returns a validated StressRequest or an error if validation fails.
+// Returns a validated StressRequest, or ValidationErrors listing every
+// malformed or out-of-range parameter. Workers above the maximum are capped
+// rather than rejected.
 func parseAndValidateStressRequest(r *http.Request) (*StressRequest, error) {
+	cfg := config.Current().Stress
 	numCPU := runtime.NumCPU()
 	maxWorkers := numCPU * 2
+	query := r.URL.Query()
 
-	// Parse duration (default: 2s)
-	durationSeconds := int(defaultStressDuration.Seconds())
-	if durationStr := r.URL.Query().Get("duration"); durationStr != "" {
-		if d, err := time.ParseDuration(durationStr); err == nil {
-			durationSeconds = int(d.Seconds())
-		}
+	// Defaults: configured default duration, one worker per CPU core
+	req := &StressRequest{
+		Duration: cfg.DefaultDuration,
+		Workers:  numCPU,
 	}
 
-	// Parse workers (default: number of CPU cores)
-	workers := numCPU
-	if workersStr := r.URL.Query().Get("workers"); workersStr != "" {
-		if w, err := strconv.Atoi(workersStr); err == nil {
-			workers = w
+	var errs ValidationErrors
+
+	if value := query.Get("duration"); value != "" {
+		d, err := time.ParseDuration(value)
+		switch {
+		case err != nil:
+			errs = append(errs, durationValidationError(value, "duration must be a duration such as 500ms or 10s", cfg.MaxDuration))
+		case d < minStressDuration || d > cfg.MaxDuration:
+			errs = append(errs, durationValidationError(value, "", cfg.MaxDuration))
+		default:
+			req.Duration = d
 		}
 	}
 
-	// Create request struct for validation
-	req := &StressRequest{
-		DurationSeconds: durationSeconds,
-		Workers:         workers,
+	if value := query.Get("workers"); value != "" {
+		w, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			errs = append(errs, workersValidationError(value, "workers must be a whole number", maxWorkers))
+		case w < 1:
+			errs = append(errs, workersValidationError(value, "", maxWorkers))
+		default:
+			// Cap rather than reject, so load tests written for larger
+			// nodes keep working
+			req.Workers = min(w, maxWorkers)
+		}
 	}
 
-	// Validate using struct tags
-	if err := validate.Struct(req); err != nil {
-		// Translate validation errors to user-friendly messages
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			return nil, formatValidationError(validationErrors, maxWorkers)
-		}
-		return nil, err
+	if len(errs) > 0 {
+		return nil, errs
 	}
+	return req, nil
+}
 
-	// Apply max bounds (validator doesn't support dynamic max)
-	if req.DurationSeconds > int(maxStressDuration.Seconds()) {
-		req.DurationSeconds = int(maxStressDuration.Seconds())
+// durationValidationError returns the error for an invalid stress duration,
+// with message or, if empty, the allowed range as its message.
+func durationValidationError(value, message string, maxDuration time.Duration) *ValidationError {
+	if message == "" {
+		message = "duration must be between " + minStressDuration.String() + " and " + maxDuration.String()
 	}
-	if req.Workers > maxWorkers {
-		req.Workers = maxWorkers
+	return &ValidationError{
+		Field:   "duration",
+		Message: message,
+		Value:   value,
+		Min:     minStressDuration.String(),
+		Max:     maxDuration.String(),
 	}
-
-	return req, nil
 }
 
-// formatValidationError converts validator.ValidationErrors to a user-friendly error.
-func formatValidationError(errors validator.ValidationErrors, maxWorkers int) error {
-	for _, err := range errors {
-		switch err.Field() {
-		case "DurationSeconds":
-			return &ValidationError{
-				Field:   "duration",
-				Message: "duration must be between 1s and 30s",
-			}
-		case "Workers":
-			return &ValidationError{
-				Field:   "workers",
-				Message: "workers must be between 1 and " + strconv.Itoa(maxWorkers),
-			}
-		}
+// workersValidationError returns the error for an invalid stress worker
+// count, with message or, if empty, the allowed range as its message.
+func workersValidationError(value, message string, maxWorkers int) *ValidationError {
+	if message == "" {
+		message = "workers must be between 1 and " + strconv.Itoa(maxWorkers)
+	}
+	return &ValidationError{
+		Field:   "workers",
+		Message: message,
+		Value:   value,
+		Min:     1,
+		Max:     maxWorkers,
 	}
-	return &ValidationError{Field: "unknown", Message: "validation failed"}
 }
 
 // ValidationError represents a validation error for a specific field.
 type ValidationError struct {
 	Field   string
 	Message string
+
+	// Value is the rejected value as given in the request, if any.
+	Value string
+
+	// Min and Max are the bounds of the field, if it has any.
+	Min, Max any
 }
 
 // Error implements the error interface.
@@ -248,38 +398,126 @@ func (e *ValidationError) Error() string {
 	return e.Message
 }
 
+// ValidationErrors reports every invalid field of a request at once.
+type ValidationErrors []*ValidationError
+
+// Error implements the error interface, joining the messages of all errors.
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// fieldErrors returns the invalid fields described by err, for the errors
+// member of problem details responses (see response.SendError), or nil if err
+// is not a validation error.
+func fieldErrors(err error) []response.FieldError {
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil
+		}
+		validationErrs = ValidationErrors{validationErr}
+	}
+
+	fields := make([]response.FieldError, len(validationErrs))
+	for i, e := range validationErrs {
+		fields[i] = response.FieldError{
+			Field:   e.Field,
+			Message: e.Message,
+			Value:   e.Value,
+			Min:     e.Min,
+			Max:     e.Max,
+		}
+	}
+	return fields
+}
+
 // runMultiCoreStress executes CPU-intensive work across multiple goroutines.
 // Each worker performs continuous math operations to consume CPU cycles.
-func runMultiCoreStress(duration time.Duration, workers int) {
+//
+// Workers stop early when ctx is cancelled. The returned outcome is
+// outcomeCompleted if every worker ran for the full duration and
+// outcomeCancelled otherwise. The workers are counted in the saturation
+// metrics of route, and each one runs in a child span of the span in ctx
+// recording its worker ID and loop iterations.
+func (a *App) runMultiCoreStress(ctx context.Context, route string, duration time.Duration, workers int) string {
 	var wg sync.WaitGroup
+	var cancelled atomic.Bool
+
+	a.activeStressWorkers.Add(int64(workers))
+	defer a.activeStressWorkers.Add(-int64(workers))
+
+	a.rec.AddStressWorkers(route, workers)
+	defer a.rec.AddStressWorkers(route, -workers)
 
 	// Launch worker goroutines
 	for i := range workers {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			stressWorker(duration, workerID)
+
+			workerCtx, span := tracing.Tracer().Start(ctx, "stress.worker",
+				trace.WithAttributes(
+					attribute.String("stress.route", route),
+					attribute.Int("stress.worker.id", workerID),
+					attribute.String("stress.target_duration", duration.String()),
+				),
+			)
+			defer span.End()
+
+			completed, iterations := stressWorker(workerCtx, duration, workerID)
+			span.SetAttributes(
+				attribute.Int64("stress.worker.iterations", iterations),
+				attribute.Bool("stress.worker.completed", completed),
+			)
+			if !completed {
+				cancelled.Store(true)
+			}
 		}(i)
 	}
 
 	// Wait for all workers to complete
 	wg.Wait()
+
+	if cancelled.Load() {
+		return outcomeCancelled
+	}
+	return outcomeCompleted
 }
 
 // stressWorker performs CPU-intensive calculations for the specified duration.
-// It runs a tight loop of math operations to maximize CPU utilization.
-// The workerID is used for logging to identify individual workers.
-func stressWorker(duration time.Duration, workerID int) {
-	logger.Debug().
+// It runs a tight loop of math operations to maximize CPU utilization and
+// returns early if ctx is cancelled. It reports whether the full duration
+// was completed and how many loop iterations ran. The workerID is used for
+// logging to identify individual workers.
+func stressWorker(ctx context.Context, duration time.Duration, workerID int) (completed bool, iterations int64) {
+	logger.FromContext(ctx).Debug().
 		Int("worker_id", workerID).
 		Dur("target_duration", duration).
 		Msg("Stress worker started")
 
 	start := time.Now()
+	done := ctx.Done()
 
 	// Use multiple math operations to maximize CPU usage
 	var result float64
 	for time.Since(start) < duration {
+		select {
+		case <-done:
+			logger.FromContext(ctx).Debug().
+				Int("worker_id", workerID).
+				Int64("iterations", iterations).
+				Dur("elapsed", time.Since(start)).
+				Msg("Stress worker cancelled")
+			return false, iterations
+		default:
+		}
+		iterations++
+
 		// Mix of operations to prevent compiler optimization
 		result = math.Sqrt(float64(time.Now().UnixNano()))
 		result = math.Sin(result) * math.Cos(result)
@@ -288,8 +526,10 @@ func stressWorker(duration time.Duration, workerID int) {
 	}
 
 	elapsed := time.Since(start)
-	logger.Debug().
+	logger.FromContext(ctx).Debug().
 		Int("worker_id", workerID).
+		Int64("iterations", iterations).
 		Dur("elapsed", elapsed).
 		Msg("Stress worker finished")
+	return true, iterations
 }
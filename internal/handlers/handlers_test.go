@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+)
+
+// fields returns the names of the invalid fields in err, in order.
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error %v (%T) is not ValidationErrors", err, err)
+	}
+	names := make([]string, len(errs))
+	for i, e := range errs {
+		names[i] = e.Field
+	}
+	return names
+}
+
+func TestParseAndValidateStressRequest(t *testing.T) {
+	numCPU := runtime.NumCPU()
+
+	tests := []struct {
+		name        string
+		query       string
+		wantFields  []string
+		wantWorkers int
+		wantDur     time.Duration
+	}{
+		{name: "defaults", query: "", wantWorkers: numCPU, wantDur: 2 * time.Second},
+		{name: "valid", query: "duration=1500ms&workers=1", wantWorkers: 1, wantDur: 1500 * time.Millisecond},
+		{name: "workers are capped", query: "workers=100000", wantWorkers: 2 * numCPU, wantDur: 2 * time.Second},
+		{name: "malformed duration", query: "duration=soon", wantFields: []string{"duration"}},
+		{name: "duration too long", query: "duration=1h", wantFields: []string{"duration"}},
+		{name: "malformed workers", query: "workers=two", wantFields: []string{"workers"}},
+		{name: "all invalid", query: "duration=0s&workers=0", wantFields: []string{"duration", "workers"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := parseAndValidateStressRequest(httptest.NewRequest(http.MethodGet, "/stress?"+tt.query, nil))
+
+			if tt.wantFields != nil {
+				if got := fields(t, err); !slices.Equal(got, tt.wantFields) {
+					t.Errorf("invalid fields = %v, want %v", got, tt.wantFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req.Workers != tt.wantWorkers || req.Duration != tt.wantDur {
+				t.Errorf("request = %+v, want %d workers for %v", req, tt.wantWorkers, tt.wantDur)
+			}
+		})
+	}
+}
+
+func TestValidationErrorsReportBoundsAndValues(t *testing.T) {
+	_, err := parseAndValidateStressRequest(httptest.NewRequest(http.MethodGet, "/stress?duration=1h&workers=-3", nil))
+
+	want := "duration must be between " + minStressDuration.String() + " and 30s; workers must be between 1 and " + strconv.Itoa(2*runtime.NumCPU())
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	got := fieldErrors(err)
+	if len(got) != 2 {
+		t.Fatalf("fieldErrors() = %+v, want 2 errors", got)
+	}
+	if got[0].Value != "1h" || got[0].Min != minStressDuration.String() || got[0].Max != "30s" {
+		t.Errorf("duration field error = %+v", got[0])
+	}
+	if got[1].Value != "-3" || got[1].Min != 1 || got[1].Max != 2*runtime.NumCPU() {
+		t.Errorf("workers field error = %+v", got[1])
+	}
+
+	single := fieldErrors(&ValidationError{Field: "size", Message: "bad"})
+	if len(single) != 1 || single[0].Field != "size" {
+		t.Errorf("fieldErrors(*ValidationError) = %+v", single)
+	}
+	if fieldErrors(errors.New("other")) != nil {
+		t.Error("fieldErrors() of a non-validation error is not nil")
+	}
+}
+
+// setCgroupMemory points the cgroup v2 memory files at temporary files
+// reporting the given limit and usage in MiB, or at missing files if limit
+// is negative.
+func setCgroupMemory(t *testing.T, limitMiB, usageMiB int) {
+	t.Helper()
+
+	dir := t.TempDir()
+	write := func(name string, mib int) string {
+		path := filepath.Join(dir, name)
+		if mib >= 0 {
+			if err := os.WriteFile(path, []byte(strconv.Itoa(mib*mebibyte)+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+
+	saved := []string{cgroupV2MemoryLimit, cgroupV2MemoryUsage, cgroupV1MemoryLimit, cgroupV1MemoryUsage}
+	cgroupV2MemoryLimit = write("memory.max", limitMiB)
+	cgroupV2MemoryUsage = write("memory.current", usageMiB)
+	cgroupV1MemoryLimit = filepath.Join(dir, "missing")
+	cgroupV1MemoryUsage = filepath.Join(dir, "missing")
+	t.Cleanup(func() {
+		cgroupV2MemoryLimit, cgroupV2MemoryUsage, cgroupV1MemoryLimit, cgroupV1MemoryUsage = saved[0], saved[1], saved[2], saved[3]
+	})
+}
+
+func TestParseAndValidateMemoryStressRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		limitMiB   int
+		usageMiB   int
+		wantFields []string
+		wantErr    error
+		want       *MemoryStressRequest
+	}{
+		{
+			name:     "defaults",
+			limitMiB: -1,
+			want:     &MemoryStressRequest{SizeMiB: defaultMemoryStressMiB, Duration: defaultMemoryStressDuration},
+		},
+		{
+			name:     "sub-second durations are kept",
+			query:    "size=4&duration=1500ms&ramp=750ms&allow_oom=false",
+			limitMiB: -1,
+			want:     &MemoryStressRequest{SizeMiB: 4, Duration: 1500 * time.Millisecond, Ramp: 750 * time.Millisecond},
+		},
+		{
+			name:       "malformed values are rejected",
+			query:      "size=lots&duration=forever&ramp=slowly&allow_oom=maybe",
+			limitMiB:   -1,
+			wantFields: []string{"duration", "ramp", "allow_oom", "size"},
+		},
+		{
+			name:       "out of range values are rejected",
+			query:      "size=0&duration=10m&ramp=20s",
+			limitMiB:   -1,
+			wantFields: []string{"duration", "size"},
+		},
+		{
+			name:       "ramp longer than duration",
+			query:      "duration=5s&ramp=6s",
+			limitMiB:   -1,
+			wantFields: []string{"ramp"},
+		},
+		{
+			name:       "size above the cgroup limit",
+			query:      "size=100",
+			limitMiB:   128,
+			usageMiB:   32,
+			wantFields: []string{"size"},
+		},
+		{
+			name:     "allow_oom skips the cgroup limit",
+			query:    "size=1000&allow_oom=true",
+			limitMiB: 128,
+			usageMiB: 32,
+			want:     &MemoryStressRequest{SizeMiB: 1000, Duration: defaultMemoryStressDuration, AllowOOM: true},
+		},
+		{
+			name:     "no memory available",
+			query:    "size=1",
+			limitMiB: 64,
+			usageMiB: 60,
+			wantErr:  errNoMemoryAvailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCgroupMemory(t, tt.limitMiB, tt.usageMiB)
+
+			req, err := parseAndValidateMemoryStressRequest(httptest.NewRequest(http.MethodGet, "/stress/memory?"+tt.query, nil))
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantFields != nil:
+				if got := fields(t, err); !slices.Equal(got, tt.wantFields) {
+					t.Errorf("invalid fields = %v, want %v", got, tt.wantFields)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case *req != *tt.want:
+				t.Errorf("request = %+v, want %+v", req, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStressHandlerNoMemoryAvailable(t *testing.T) {
+	setCgroupMemory(t, 64, 64)
+
+	rec := httptest.NewRecorder()
+	New(metrics.Nop()).MemoryStressHandler(rec, httptest.NewRequest(http.MethodGet, "/stress/memory?size=1", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body)
+	}
+}
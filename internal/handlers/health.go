@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/moabdelazem/go-gitops-app/internal/health"
+	"github.com/moabdelazem/go-gitops-app/pkg/config"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// Probe state is process-wide rather than per App: Kubernetes probes the
+// whole process, so the health registry, draining and started are shared by
+// every App in it.
+
+// draining reports whether the server has started shutting down. Once set,
+// the readiness probe fails so Kubernetes stops routing new traffic here.
+var draining atomic.Bool
+
+// started reports whether the server has finished initialization and is
+// accepting connections. The startup probe fails until it is set.
+var started atomic.Bool
+
+// saturationApps holds the Apps whose stress workers count towards the
+// stress-saturation readiness check. The workers of every App compete for
+// the same CPUs, so the check sums them.
+var saturationApps = struct {
+	sync.Mutex
+	apps []*App
+}{}
+
+// RegisterHealthChecks registers the handler package's checks with the
+// process-wide health registry and adds the App's stress workers to the
+// saturation check. It should be called once per App during startup;
+// further calls have no effect.
+//
+// Registered checks:
+//   - ping (liveness): always passes while the process can serve HTTP
+//   - started (startup): passes once MarkStarted has been called
+//   - draining (readiness): fails once SetDraining has been called
+//   - stress-saturation (readiness): fails while at least the configured
+//     number of saturation workers are running across all registered Apps,
+//     shedding traffic from a saturated pod
+func (a *App) RegisterHealthChecks() {
+	saturationApps.Lock()
+	defer saturationApps.Unlock()
+
+	if slices.Contains(saturationApps.apps, a) {
+		return
+	}
+	saturationApps.apps = append(saturationApps.apps, a)
+
+	health.Register("ping", func(ctx context.Context) error {
+		return nil
+	}, health.Liveness)
+
+	health.Register("started", func(ctx context.Context) error {
+		if !started.Load() {
+			return errors.New("server is still starting")
+		}
+		return nil
+	}, health.Startup)
+
+	health.Register("draining", func(ctx context.Context) error {
+		if draining.Load() {
+			return errors.New("server is shutting down")
+		}
+		return nil
+	}, health.Readiness)
+
+	health.Register("stress-saturation", func(ctx context.Context) error {
+		saturationWorkers := config.Current().Stress.SaturationWorkers
+		if active := activeStressWorkers(); active >= int64(saturationWorkers) {
+			return fmt.Errorf("%d stress workers running, saturation threshold is %d", active, saturationWorkers)
+		}
+		return nil
+	}, health.Readiness)
+}
+
+// activeStressWorkers returns the number of stress workers running across
+// all Apps that registered their health checks.
+func activeStressWorkers() int64 {
+	saturationApps.Lock()
+	defer saturationApps.Unlock()
+
+	var active int64
+	for _, a := range saturationApps.apps {
+		active += a.activeStressWorkers.Load()
+	}
+	return active
+}
+
+// MarkStarted marks the server as started, allowing the startup probe to pass.
+func MarkStarted() {
+	started.Store(true)
+}
+
+// SetDraining marks the server as draining. The readiness probe fails from
+// this point on; liveness is unaffected so the pod is not restarted mid-drain.
+func SetDraining() {
+	draining.Store(true)
+}
+
+// LivenessHandler handles Kubernetes liveness probe requests.
+//
+// Endpoint: GET /livez
+// Response: See probeHandler.
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	probeHandler(w, r, health.Liveness)
+}
+
+// ReadinessHandler handles Kubernetes readiness probe requests. It fails while
+// the server is draining or saturated with stress workers.
+//
+// Endpoint: GET /readyz
+// Response: See probeHandler.
+func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	probeHandler(w, r, health.Readiness)
+}
+
+// StartupHandler handles Kubernetes startup probe requests. It fails until
+// the server has finished initialization.
+//
+// Endpoint: GET /startupz
+// Response: See probeHandler.
+func StartupHandler(w http.ResponseWriter, r *http.Request) {
+	probeHandler(w, r, health.Startup)
+}
+
+// HealthHandler handles legacy health check requests. It is kept for
+// backward compatibility with existing clients and load test scripts, and
+// behaves exactly like LivenessHandler.
+//
+// Endpoint: GET /health
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	probeHandler(w, r, health.Liveness)
+}
+
+// probeHandler evaluates all checks registered for the probe and writes the
+// result. It returns 200 when every check passes and 503 otherwise.
+//
+// By default the body is plain text "OK" or "FAIL". When the verbose query
+// parameter is present (e.g. /readyz?verbose), the full per-check report is
+// returned as JSON instead.
+//
+// Like every request, probes are logged and counted in the request metrics
+// by the Logging middleware; only the scaling signals leave them out (see
+// middleware.Scaling). The handler itself logs failing probes at warn level
+// and passing ones at debug level only.
+func probeHandler(w http.ResponseWriter, r *http.Request, probe health.Probe) {
+	report := health.Run(r.Context(), probe)
+
+	statusCode := http.StatusOK
+	if !report.Healthy() {
+		statusCode = http.StatusServiceUnavailable
+
+		// Failing probes are worth surfacing; passing ones are debug noise
+		logger.FromContext(r.Context()).Warn().
+			Str("path", r.URL.Path).
+			Str("probe", string(probe)).
+			Interface("checks", report.Checks).
+			Msg("Health probe failing")
+	} else {
+		logger.FromContext(r.Context()).Debug().
+			Str("path", r.URL.Path).
+			Str("probe", string(probe)).
+			Msg("Health probe passed")
+	}
+
+	if r.URL.Query().Has("verbose") {
+		response.Send(w, r, statusCode, report)
+		return
+	}
+
+	w.WriteHeader(statusCode)
+	if report.Healthy() {
+		_, _ = w.Write([]byte("OK"))
+	} else {
+		_, _ = w.Write([]byte("FAIL"))
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/moabdelazem/go-gitops-app/internal/health"
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+)
+
+// readinessCheck returns the result of the named readiness check.
+func readinessCheck(t *testing.T, name string) health.CheckResult {
+	t.Helper()
+
+	for _, check := range health.Run(context.Background(), health.Readiness).Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no readiness check named %s", name)
+	return health.CheckResult{}
+}
+
+func TestStressSaturationCoversEveryApp(t *testing.T) {
+	first, second := New(metrics.Nop()), New(metrics.Nop())
+	first.RegisterHealthChecks()
+	second.RegisterHealthChecks()
+	second.RegisterHealthChecks()
+
+	// The default threshold is two workers per CPU
+	threshold := int64(2 * runtime.NumCPU())
+	first.activeStressWorkers.Add(threshold - 1)
+	t.Cleanup(func() { first.activeStressWorkers.Add(1 - threshold) })
+
+	if check := readinessCheck(t, "stress-saturation"); check.Status != health.StatusPass {
+		t.Fatalf("stress-saturation = %+v below the threshold, want pass", check)
+	}
+
+	// Registering the second App kept the first one's workers counted
+	second.activeStressWorkers.Add(1)
+	t.Cleanup(func() { second.activeStressWorkers.Add(-1) })
+
+	if check := readinessCheck(t, "stress-saturation"); check.Status != health.StatusFail {
+		t.Errorf("stress-saturation = %+v at the threshold across both Apps, want fail", check)
+	}
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/config"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+	"github.com/moabdelazem/go-gitops-app/pkg/tracing"
+)
+
+const (
+	// maxRetainedStressJobs caps how many finished jobs are kept for status
+	// queries. The oldest finished jobs are evicted first.
+	maxRetainedStressJobs = 100
+
+	// jobRunning is the status of a stress job that has not finished yet.
+	// Finished jobs report their run outcome as status.
+	jobRunning = "running"
+
+	// jobCancelWait bounds how long a cancellation request waits for the
+	// job's workers to stop.
+	jobCancelWait = 2 * time.Second
+)
+
+// errJobCancelled is the cancellation cause for jobs stopped via DELETE.
+var errJobCancelled = errors.New("cancelled by client")
+
+// StressJob is the externally visible state of a background stress job.
+type StressJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Duration   string     `json:"duration"`
+	Workers    int        `json:"workers"`
+	Progress   float64    `json:"progress"`
+	Elapsed    string     `json:"elapsed"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// StressJobList is the response body for listing stress jobs.
+type StressJobList struct {
+	Count int         `json:"count"`
+	Jobs  []StressJob `json:"jobs"`
+}
+
+// stressJob tracks a single background stress run.
+type stressJob struct {
+	id        string
+	duration  time.Duration
+	workers   int
+	startedAt time.Time
+	cancel    context.CancelCauseFunc
+
+	// done is closed when the job finishes.
+	done chan struct{}
+
+	mu         sync.Mutex
+	status     string
+	finishedAt time.Time
+}
+
+// snapshot returns the job's current state for API responses.
+func (j *stressJob) snapshot() StressJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	end := time.Now()
+	job := StressJob{
+		ID:        j.id,
+		Status:    j.status,
+		Duration:  j.duration.String(),
+		Workers:   j.workers,
+		StartedAt: j.startedAt,
+	}
+	if j.status != jobRunning {
+		end = j.finishedAt
+		finishedAt := j.finishedAt
+		job.FinishedAt = &finishedAt
+	}
+
+	elapsed := end.Sub(j.startedAt)
+	job.Elapsed = elapsed.String()
+	job.Progress = math.Min(1, math.Round(elapsed.Seconds()/j.duration.Seconds()*1000)/1000)
+	if job.Status == outcomeCompleted {
+		job.Progress = 1
+	}
+	return job
+}
+
+// finish records the final outcome of the job and closes done.
+func (j *stressJob) finish(outcome string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.status = outcome
+	j.finishedAt = time.Now()
+	close(j.done)
+}
+
+// running reports whether the job is still executing.
+func (j *stressJob) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.status == jobRunning
+}
+
+// jobRegistry is the in-memory registry of background stress jobs. Jobs are
+// kept in creation order so listings are stable.
+type jobRegistry struct {
+	sync.Mutex
+	byID  map[string]*stressJob
+	order []string
+}
+
+// CreateStressJobHandler starts a stress test in the background and returns
+// immediately with the new job's ID.
+//
+// Endpoint: POST /stress/jobs
+//
+// Query Parameters: Same as StressHandler (duration, workers).
+//
+// Response: 202 Accepted with the job state and a Location header pointing
+// to the job. Returns 429 if the configured maximum number of jobs is
+// already running.
+func (a *App) CreateStressJobHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := parseAndValidateStressRequest(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn().
+			Err(err).
+			Str("path", r.URL.Path).
+			Msg("Invalid stress job parameters")
+
+		response.SendError(w, r, http.StatusBadRequest, err.Error(), fieldErrors(err)...)
+		return
+	}
+
+	job, err := a.startStressJob(r.Context(), req.Duration, req.Workers)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn().
+			Err(err).
+			Str("path", r.URL.Path).
+			Msg("Stress job rejected")
+
+		response.SendError(w, r, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	logger.FromContext(r.Context()).Warn().
+		Str("job_id", job.id).
+		Str("remote_addr", r.RemoteAddr).
+		Dur("duration", job.duration).
+		Int("workers", job.workers).
+		Msg("Background stress job started - CPU spike incoming")
+
+	w.Header().Set("Location", "/stress/jobs/"+job.id)
+	response.Send(w, r, http.StatusAccepted, job.snapshot())
+}
+
+// ListStressJobsHandler lists running and recently finished stress jobs in
+// creation order.
+//
+// Endpoint: GET /stress/jobs
+// Response: JSON with the job count and the state of each job.
+func (a *App) ListStressJobsHandler(w http.ResponseWriter, r *http.Request) {
+	a.jobs.Lock()
+	list := make([]*stressJob, 0, len(a.jobs.order))
+	for _, id := range a.jobs.order {
+		list = append(list, a.jobs.byID[id])
+	}
+	a.jobs.Unlock()
+
+	resp := StressJobList{Jobs: make([]StressJob, 0, len(list))}
+	for _, job := range list {
+		resp.Jobs = append(resp.Jobs, job.snapshot())
+	}
+	resp.Count = len(resp.Jobs)
+
+	response.Send(w, r, http.StatusOK, resp)
+}
+
+// GetStressJobHandler returns the status, progress and elapsed time of a
+// stress job.
+//
+// Endpoint: GET /stress/jobs/{id}
+// Response: JSON job state, or 404 if the job is unknown or was evicted.
+func (a *App) GetStressJobHandler(w http.ResponseWriter, r *http.Request) {
+	job := a.lookupStressJob(mux.Vars(r)["id"])
+	if job == nil {
+		response.SendError(w, r, http.StatusNotFound, "stress job not found")
+		return
+	}
+
+	response.Send(w, r, http.StatusOK, job.snapshot())
+}
+
+// CancelStressJobHandler cancels a running stress job. Workers stop promptly
+// and the job finishes with status "cancelled". The handler waits up to
+// jobCancelWait for that, so the response normally reports the final state.
+//
+// Endpoint: DELETE /stress/jobs/{id}
+// Response: JSON job state, 200 once the job has finished, or 202 Accepted
+// with the job still "running" if it did not stop in time; poll the job to
+// see it finish. 404 if the job is unknown, or 409 if the job has already
+// finished.
+func (a *App) CancelStressJobHandler(w http.ResponseWriter, r *http.Request) {
+	job := a.lookupStressJob(mux.Vars(r)["id"])
+	if job == nil {
+		response.SendError(w, r, http.StatusNotFound, "stress job not found")
+		return
+	}
+
+	if !job.running() {
+		response.SendError(w, r, http.StatusConflict, "stress job has already finished")
+		return
+	}
+
+	job.cancel(errJobCancelled)
+
+	logger.FromContext(r.Context()).Info().
+		Str("job_id", job.id).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Stress job cancellation requested")
+
+	timer := time.NewTimer(jobCancelWait)
+	defer timer.Stop()
+
+	statusCode := http.StatusOK
+	select {
+	case <-job.done:
+	case <-timer.C:
+		statusCode = http.StatusAccepted
+	case <-r.Context().Done():
+		statusCode = http.StatusAccepted
+	}
+
+	response.Send(w, r, statusCode, job.snapshot())
+}
+
+// lookupStressJob returns the job with the given ID, or nil if none exists.
+func (a *App) lookupStressJob(id string) *stressJob {
+	a.jobs.Lock()
+	defer a.jobs.Unlock()
+
+	return a.jobs.byID[id]
+}
+
+// startStressJob registers a new job and starts its workers in the
+// background. Jobs are not tied to the request context; they stop when they
+// complete, are cancelled via the API, or the server shuts down. Each job
+// runs in the root span of a new trace linked to the span in reqCtx, so long
+// runs don't stretch the trace of the request that started them.
+func (a *App) startStressJob(reqCtx context.Context, duration time.Duration, workers int) (*stressJob, error) {
+	a.jobs.Lock()
+	defer a.jobs.Unlock()
+
+	running := 0
+	for _, job := range a.jobs.byID {
+		if job.running() {
+			running++
+		}
+	}
+	if running >= config.Current().Stress.MaxRunningJobs {
+		return nil, errors.New("too many stress jobs running, try again later")
+	}
+
+	jobID := newJobID()
+	ctx, span := tracing.Tracer().Start(context.Background(), "stress.job",
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(reqCtx)),
+		trace.WithAttributes(
+			attribute.String("stress.job.id", jobID),
+			attribute.Int("stress.workers", workers),
+			attribute.String("stress.target_duration", duration.String()),
+		),
+	)
+
+	// Keep logging through the request logger, so the job's log lines carry
+	// the ID of the request that started it
+	ctx = logger.WithContext(ctx, *logger.FromContext(reqCtx))
+
+	ctx, cancel := a.stressContext(ctx)
+	ctx, cancelCause := context.WithCancelCause(ctx)
+
+	job := &stressJob{
+		id:        jobID,
+		duration:  duration,
+		workers:   workers,
+		startedAt: time.Now(),
+		cancel:    cancelCause,
+		done:      make(chan struct{}),
+		status:    jobRunning,
+	}
+	a.jobs.byID[job.id] = job
+	a.jobs.order = append(a.jobs.order, job.id)
+	a.evictFinishedStressJobs()
+
+	a.beginStressRun()
+	go func() {
+		defer a.endStressRun()
+		defer cancel()
+		defer span.End()
+
+		outcome := a.runMultiCoreStress(ctx, routeStressJobs, duration, workers)
+		job.finish(outcome)
+		a.rec.TrackStressRun("cpu", outcome)
+		span.SetAttributes(attribute.String("stress.outcome", outcome))
+
+		logger.FromContext(ctx).Info().
+			Str("job_id", job.id).
+			Str("outcome", outcome).
+			AnErr("reason", context.Cause(ctx)).
+			Dur("elapsed", time.Since(job.startedAt)).
+			Int("workers", workers).
+			Msg("Background stress job finished")
+	}()
+
+	return job, nil
+}
+
+// evictFinishedStressJobs drops the oldest finished jobs once more than
+// maxRetainedStressJobs are tracked. Running jobs are never evicted.
+// The caller must hold the jobs lock.
+func (a *App) evictFinishedStressJobs() {
+	excess := len(a.jobs.order) - maxRetainedStressJobs
+	if excess <= 0 {
+		return
+	}
+
+	kept := a.jobs.order[:0]
+	for _, id := range a.jobs.order {
+		if excess > 0 && !a.jobs.byID[id].running() {
+			delete(a.jobs.byID, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	a.jobs.order = kept
+}
+
+// newJobID returns a random 16 character hex identifier.
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
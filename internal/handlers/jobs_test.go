@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+)
+
+func TestCancelStressJobReturnsFinalState(t *testing.T) {
+	app := New(metrics.Nop())
+	router := mux.NewRouter()
+	router.HandleFunc("/stress/jobs", app.CreateStressJobHandler).Methods(http.MethodPost)
+	router.HandleFunc("/stress/jobs/{id}", app.CancelStressJobHandler).Methods(http.MethodDelete)
+
+	do := func(method, path string) (int, StressJob) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+
+		var job StressJob
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			t.Fatalf("%s %s: decode body: %v", method, path, err)
+		}
+		return rec.Code, job
+	}
+
+	code, job := do(http.MethodPost, "/stress/jobs?duration=30s&workers=1")
+	if code != http.StatusAccepted || job.Status != jobRunning {
+		t.Fatalf("create: status %d, job %+v; want 202 and a running job", code, job)
+	}
+
+	code, job = do(http.MethodDelete, "/stress/jobs/"+job.ID)
+	if code != http.StatusOK {
+		t.Fatalf("cancel: status = %d, want %d", code, http.StatusOK)
+	}
+	if job.Status != outcomeCancelled || job.FinishedAt == nil {
+		t.Errorf("cancel: job = %+v, want status %q with finished_at", job, outcomeCancelled)
+	}
+
+	if code, _ := do(http.MethodDelete, "/stress/jobs/"+job.ID); code != http.StatusConflict {
+		t.Errorf("second cancel: status = %d, want %d", code, http.StatusConflict)
+	}
+}
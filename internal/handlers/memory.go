@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/config"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+const (
+	// Default memory stress configuration
+	defaultMemoryStressMiB      = 32
+	defaultMemoryStressDuration = 10 * time.Second
+
+	// Bounds of the memory stress duration, including the ramp
+	minMemoryStressDuration = time.Second
+	maxMemoryStressDuration = 5 * time.Minute
+
+	// memoryHeadroomMiB is reserved below the cgroup limit for the Go
+	// runtime and the rest of the application.
+	memoryHeadroomMiB = 16
+
+	// pageSize is the stride used to touch allocated memory. Writing one
+	// byte per page forces the kernel to back every page with real memory.
+	pageSize = 4096
+
+	mebibyte = 1 << 20
+)
+
+// Cgroup files exposing the container's memory limit and current usage.
+// cgroup v2 is tried first, then cgroup v1.
+var (
+	cgroupV2MemoryLimit = "/sys/fs/cgroup/memory.max"
+	cgroupV2MemoryUsage = "/sys/fs/cgroup/memory.current"
+	cgroupV1MemoryLimit = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+	cgroupV1MemoryUsage = "/sys/fs/cgroup/memory/memory.usage_in_bytes"
+)
+
+// errNoMemoryAvailable is returned by parseAndValidateMemoryStressRequest
+// when the container has no memory left to allocate below its cgroup limit.
+var errNoMemoryAvailable = errors.New("no memory available for a memory stress test; the container is at its memory limit (set allow_oom=true to allocate anyway)")
+
+// MemoryStressRequest represents the validated parameters for a memory stress test.
+type MemoryStressRequest struct {
+	// SizeMiB is the amount of memory to allocate in MiB (1 to the cgroup
+	// limit minus current usage and headroom, unless AllowOOM is set).
+	SizeMiB int
+
+	// Duration is how long to hold the memory (minMemoryStressDuration to
+	// maxMemoryStressDuration), including the ramp.
+	Duration time.Duration
+
+	// Ramp spreads the allocation over this duration (0 to Duration).
+	Ramp time.Duration
+
+	// AllowOOM skips the cgroup limit check so the container can be pushed
+	// into an OOMKill deliberately.
+	AllowOOM bool
+}
+
+// MemoryStressHandler simulates memory pressure to trigger a memory-based
+// Horizontal Pod Autoscaler or to demonstrate OOMKill behavior.
+// It allocates the requested amount of memory, touches every page so it is
+// resident, holds it for the requested duration and then releases it.
+//
+// Endpoint: GET /stress/memory
+//
+// Query Parameters:
+//   - size: Memory to allocate in MiB. Default: 32. Max: cgroup limit minus
+//     current usage and headroom, or the configured maximum when no limit applies
+//   - duration: How long to hold the memory (e.g., "30s"). Default: 10s, Max: 5m
+//   - ramp: Spread the allocation over this duration. Default: 0 (allocate at once)
+//   - allow_oom: Set to "true" to skip the cgroup limit check
+//
+// Invalid parameters are rejected with 400 Bad Request, listing every
+// invalid field. When the container is already at its memory limit, the
+// request fails with 503 Service Unavailable unless allow_oom is set.
+//
+// Examples:
+//   - GET /stress/memory?size=64
+//   - GET /stress/memory?size=96&duration=60s&ramp=30s
+//   - GET /stress/memory?size=256&allow_oom=true   (deliberate OOMKill)
+//
+// The run stops early if the client disconnects or the server shuts down.
+//
+// Response: JSON with status, outcome, duration and allocated MiB.
+//
+// ! WARNING: Like StressHandler, this endpoint is intended for testing only.
+func (a *App) MemoryStressHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := parseAndValidateMemoryStressRequest(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn().
+			Err(err).
+			Str("path", r.URL.Path).
+			Msg("Invalid memory stress request parameters")
+
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, errNoMemoryAvailable) {
+			statusCode = http.StatusServiceUnavailable
+		}
+		response.SendError(w, r, statusCode, err.Error(), fieldErrors(err)...)
+		return
+	}
+
+	logger.FromContext(r.Context()).Warn().
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Int("size_mib", req.SizeMiB).
+		Dur("duration", req.Duration).
+		Dur("ramp", req.Ramp).
+		Bool("allow_oom", req.AllowOOM).
+		Msg("Memory stress test initiated - memory spike incoming")
+
+	a.beginStressRun()
+	defer a.endStressRun()
+
+	ctx, cancel := a.stressContext(r.Context())
+	defer cancel()
+
+	start := time.Now()
+	outcome, allocated := a.runMemoryStress(ctx, req.SizeMiB, req.Ramp, req.Duration)
+	elapsed := time.Since(start)
+
+	a.rec.TrackStressRun("memory", outcome)
+
+	resp := StressResponse{
+		Status:       "stress_complete",
+		Message:      "Memory load simulation finished",
+		Outcome:      outcome,
+		Duration:     elapsed.String(),
+		AllocatedMiB: allocated,
+	}
+	statusCode := http.StatusOK
+
+	if outcome == outcomeCancelled {
+		statusCode = cancelledStatus(ctx)
+		cancelledLogEvent(r.Context(), statusCode).
+			Dur("duration", elapsed).
+			Int("allocated_mib", allocated).
+			AnErr("reason", context.Cause(ctx)).
+			Msg("Memory stress test cancelled")
+
+		resp.Status = "stress_cancelled"
+		resp.Message = "Memory load simulation cancelled before completion"
+	} else {
+		logger.FromContext(r.Context()).Info().
+			Dur("duration", elapsed).
+			Int("allocated_mib", allocated).
+			Msg("Memory stress test completed")
+	}
+
+	response.Send(w, r, statusCode, resp)
+}
+
+// parseAndValidateMemoryStressRequest extracts and validates memory stress
+// parameters from the HTTP request query string, then checks the requested
+// size against the container's memory limit.
+//
+// Returns a validated MemoryStressRequest, ValidationErrors listing every
+// invalid parameter, or errNoMemoryAvailable if the container has no memory
+// left to allocate.
+func parseAndValidateMemoryStressRequest(r *http.Request) (*MemoryStressRequest, error) {
+	query := r.URL.Query()
+
+	req := &MemoryStressRequest{
+		SizeMiB:  defaultMemoryStressMiB,
+		Duration: defaultMemoryStressDuration,
+	}
+
+	var errs ValidationErrors
+
+	// Parse the duration first, the ramp is bounded by it
+	durationValid := true
+	if value := query.Get("duration"); value != "" {
+		d, err := time.ParseDuration(value)
+		switch {
+		case err != nil:
+			errs = append(errs, memoryDurationValidationError(value, "duration must be a duration such as 30s or 2m"))
+			durationValid = false
+		case d < minMemoryStressDuration || d > maxMemoryStressDuration:
+			errs = append(errs, memoryDurationValidationError(value, ""))
+			durationValid = false
+		default:
+			req.Duration = d
+		}
+	}
+
+	if value := query.Get("ramp"); value != "" {
+		d, err := time.ParseDuration(value)
+		switch {
+		case err != nil:
+			errs = append(errs, rampValidationError(value, "ramp must be a duration such as 10s", req.Duration))
+		case d < 0 || (durationValid && d > req.Duration):
+			errs = append(errs, rampValidationError(value, "", req.Duration))
+		default:
+			req.Ramp = d
+		}
+	}
+
+	if value := query.Get("allow_oom"); value != "" {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Field:   "allow_oom",
+				Message: "allow_oom must be true or false",
+				Value:   value,
+			})
+		}
+		req.AllowOOM = allow
+	}
+
+	maxSizeMiB, limited := memoryStressLimitMiB()
+	// The limit only bounds the size while it is enforced
+	checkLimit := !limited || !req.AllowOOM
+
+	if value := query.Get("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			errs = append(errs, sizeValidationError(value, "size must be a whole number of MiB", maxSizeMiB, limited, checkLimit))
+		case size < 1 || (checkLimit && maxSizeMiB > 0 && size > maxSizeMiB):
+			errs = append(errs, sizeValidationError(value, "", maxSizeMiB, limited, checkLimit))
+		default:
+			req.SizeMiB = size
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if checkLimit && req.SizeMiB > maxSizeMiB {
+		// The size given was checked above, so this is the default size
+		// exceeding the limit, or no memory being left at all
+		if maxSizeMiB == 0 {
+			return nil, errNoMemoryAvailable
+		}
+		return nil, ValidationErrors{sizeValidationError("", "", maxSizeMiB, limited, checkLimit)}
+	}
+
+	return req, nil
+}
+
+// sizeValidationError returns the error for an invalid memory stress size,
+// with message or, if empty, the allowed range as its message. The upper
+// bound is maxSizeMiB when checkLimit is set, and none otherwise. limited
+// reports whether maxSizeMiB comes from the cgroup limit.
+func sizeValidationError(value, message string, maxSizeMiB int, limited, checkLimit bool) *ValidationError {
+	e := &ValidationError{
+		Field:   "size",
+		Message: message,
+		Value:   value,
+		Min:     1,
+	}
+	if !checkLimit {
+		if e.Message == "" {
+			e.Message = "size must be at least 1 MiB"
+		}
+		return e
+	}
+
+	e.Max = maxSizeMiB
+	if e.Message == "" {
+		e.Message = "size must be between 1 and " + strconv.Itoa(maxSizeMiB) + " MiB"
+		if limited {
+			e.Message += " (container memory limit minus current usage); set allow_oom=true to exceed it"
+		}
+	}
+	return e
+}
+
+// memoryDurationValidationError returns the error for an invalid memory
+// stress duration, with message or, if empty, the allowed range as its
+// message.
+func memoryDurationValidationError(value, message string) *ValidationError {
+	if message == "" {
+		message = "duration must be between " + minMemoryStressDuration.String() + " and " + maxMemoryStressDuration.String()
+	}
+	return &ValidationError{
+		Field:   "duration",
+		Message: message,
+		Value:   value,
+		Min:     minMemoryStressDuration.String(),
+		Max:     maxMemoryStressDuration.String(),
+	}
+}
+
+// rampValidationError returns the error for an invalid memory stress ramp,
+// with message or, if empty, the allowed range as its message. The ramp may
+// last at most duration.
+func rampValidationError(value, message string, duration time.Duration) *ValidationError {
+	if message == "" {
+		message = "ramp must be between 0s and the duration (" + duration.String() + ")"
+	}
+	return &ValidationError{
+		Field:   "ramp",
+		Message: message,
+		Value:   value,
+		Min:     "0s",
+		Max:     duration.String(),
+	}
+}
+
+// memoryStressLimitMiB returns the largest allocation a memory stress run
+// may make without exceeding the container's cgroup memory limit, and
+// whether a cgroup limit applies at all. Without a limit, the configured
+// maximum is returned, for example when running locally outside a container.
+func memoryStressLimitMiB() (int, bool) {
+	limit, usage, err := cgroupMemory()
+	if err != nil {
+		return config.Current().Stress.MaxMemoryMiB, false
+	}
+
+	available := int((limit-usage)/mebibyte) - memoryHeadroomMiB
+	return max(available, 0), true
+}
+
+// cgroupMemory reads the container's memory limit and current usage in
+// bytes from cgroup v2, falling back to cgroup v1. It returns an error if
+// neither is available or no limit is set.
+func cgroupMemory() (limit, usage int64, err error) {
+	limit, err = readCgroupInt(cgroupV2MemoryLimit)
+	if err == nil {
+		usage, err = readCgroupInt(cgroupV2MemoryUsage)
+		return limit, usage, err
+	}
+
+	limit, err = readCgroupInt(cgroupV1MemoryLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// cgroup v1 reports "no limit" as a huge page-aligned number
+	if limit >= 1<<62 {
+		return 0, 0, errors.New("no cgroup memory limit set")
+	}
+
+	usage, err = readCgroupInt(cgroupV1MemoryUsage)
+	return limit, usage, err
+}
+
+// readCgroupInt reads a single integer value from a cgroup file. The
+// cgroup v2 value "max" (no limit) is reported as an error.
+func readCgroupInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, errors.New("no cgroup memory limit set")
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// runMemoryStress allocates sizeMiB of memory in 1 MiB chunks, spreading the
+// allocations evenly over ramp, and holds it until duration has elapsed
+// since the start. The memory is then released and returned to the OS.
+//
+// The run stops early when ctx is cancelled. It returns the outcome and the
+// number of MiB that were allocated at the peak.
+func (a *App) runMemoryStress(ctx context.Context, sizeMiB int, ramp, duration time.Duration) (string, int) {
+	deadline := time.Now().Add(duration)
+	interval := ramp / time.Duration(sizeMiB)
+
+	chunks := make([][]byte, 0, sizeMiB)
+	defer func() {
+		a.rec.AddStressMemory(-float64(len(chunks) * mebibyte))
+
+		// Drop the references and hand the memory back to the OS right away
+		// instead of waiting for the scavenger
+		chunks = nil
+		runtime.GC()
+		debug.FreeOSMemory()
+	}()
+
+	outcome := outcomeCompleted
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for range sizeMiB {
+		chunk := make([]byte, mebibyte)
+		for i := 0; i < len(chunk); i += pageSize {
+			chunk[i] = 1
+		}
+		chunks = append(chunks, chunk)
+		a.rec.AddStressMemory(mebibyte)
+
+		if interval > 0 {
+			timer.Reset(interval)
+			select {
+			case <-ctx.Done():
+				return outcomeCancelled, len(chunks)
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return outcomeCancelled, len(chunks)
+		}
+	}
+
+	logger.FromContext(ctx).Debug().
+		Int("allocated_mib", len(chunks)).
+		Msg("Memory stress allocation complete, holding")
+
+	// Hold the memory for the rest of the duration
+	timer.Reset(time.Until(deadline))
+	select {
+	case <-ctx.Done():
+		outcome = outcomeCancelled
+	case <-timer.C:
+	}
+
+	return outcome, len(chunks)
+}
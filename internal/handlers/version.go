@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+	"github.com/moabdelazem/go-gitops-app/pkg/version"
+)
+
+// VersionHandler returns the build metadata of the running binary, so the
+// deployed commit can be identified from a pod.
+//
+// Endpoint: GET /version
+// Response: JSON with version, commit, build_date, go_version and dirty, or
+// another format chosen by the Accept header. The response carries an ETag
+// so clients can poll it with If-None-Match.
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	response.SendCacheable(w, r, version.Get())
+}
@@ -0,0 +1,180 @@
+// Package health provides a registry of named health checks that back the
+// Kubernetes liveness, readiness and startup probes.
+//
+// Components register checks against one or more probes. When a probe is
+// evaluated, every check registered for it is run and the probe passes only
+// if all of its checks pass. This lets independent parts of the application,
+// such as the stress engine or the shutdown sequence, influence probe results
+// without the probe handlers knowing about them.
+//
+// Example usage:
+//
+//	health.Register("database", func(ctx context.Context) error {
+//		return db.PingContext(ctx)
+//	}, health.Readiness)
+//	report := health.Run(ctx, health.Readiness)
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Probe identifies the kind of Kubernetes probe a check contributes to.
+type Probe string
+
+const (
+	// Liveness checks detect a process that is stuck and must be restarted.
+	Liveness Probe = "liveness"
+
+	// Readiness checks decide whether the pod should receive traffic.
+	Readiness Probe = "readiness"
+
+	// Startup checks gate liveness and readiness until initialization completes.
+	Startup Probe = "startup"
+)
+
+// Status values reported for individual checks and whole probes.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+)
+
+// checkTimeout bounds how long a single check may run. Kubernetes probes
+// default to a 1s timeout, so checks must stay well below that.
+const checkTimeout = 500 * time.Millisecond
+
+// CheckFunc reports the health of a component. A nil error means healthy.
+// Implementations should honor ctx cancellation: a check still running when
+// its timeout expires is reported as failed, but keeps running in the
+// background until it returns.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of a single named check.
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the aggregated outcome of all checks registered for a probe.
+type Report struct {
+	Probe  Probe         `json:"probe"`
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Healthy reports whether every check in the report passed.
+func (r Report) Healthy() bool {
+	return r.Status == StatusPass
+}
+
+// registry holds registered checks keyed by probe and check name.
+var registry = struct {
+	sync.RWMutex
+	checks map[Probe]map[string]CheckFunc
+}{
+	checks: make(map[Probe]map[string]CheckFunc),
+}
+
+// Register adds a named check to the given probes. Registering a check with a
+// name that already exists for a probe replaces the previous check.
+func Register(name string, check CheckFunc, probes ...Probe) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, probe := range probes {
+		if registry.checks[probe] == nil {
+			registry.checks[probe] = make(map[string]CheckFunc)
+		}
+		registry.checks[probe][name] = check
+	}
+}
+
+// Unregister removes a named check from all probes.
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, checks := range registry.checks {
+		delete(checks, name)
+	}
+}
+
+// Run evaluates every check registered for the probe and returns the
+// aggregated report. Checks run concurrently, each bounded by a short
+// timeout. A probe with no registered checks passes.
+func Run(ctx context.Context, probe Probe) Report {
+	registry.RLock()
+	names := make([]string, 0, len(registry.checks[probe]))
+	checks := make(map[string]CheckFunc, len(registry.checks[probe]))
+	for name, check := range registry.checks[probe] {
+		names = append(names, name)
+		checks[name] = check
+	}
+	registry.RUnlock()
+
+	// Sort names so reports are stable across calls
+	sort.Strings(names)
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, name, checks[name])
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Probe:  probe,
+		Status: StatusPass,
+		Checks: results,
+	}
+	for _, result := range results {
+		if result.Status != StatusPass {
+			report.Status = StatusFail
+			break
+		}
+	}
+	return report
+}
+
+// runCheck executes a single check with a timeout and converts its outcome
+// into a CheckResult. The check runs in its own goroutine, so one that
+// ignores ctx cannot hold up the probe past the timeout.
+func runCheck(ctx context.Context, name string, check CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	// Buffered so an abandoned check can still send its result and exit
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:     name,
+		Status:   StatusPass,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
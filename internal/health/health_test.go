@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunAggregatesChecks(t *testing.T) {
+	Register("test-pass", func(context.Context) error { return nil }, Startup)
+	Register("test-fail", func(context.Context) error { return errors.New("broken") }, Startup)
+	t.Cleanup(func() {
+		Unregister("test-pass")
+		Unregister("test-fail")
+	})
+
+	report := Run(context.Background(), Startup)
+	if report.Healthy() {
+		t.Fatalf("report is healthy with a failing check: %+v", report)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Name != "test-fail" || report.Checks[1].Name != "test-pass" {
+		t.Fatalf("checks = %+v, want test-fail and test-pass in name order", report.Checks)
+	}
+	if report.Checks[0].Error != "broken" {
+		t.Errorf("error = %q, want %q", report.Checks[0].Error, "broken")
+	}
+}
+
+func TestRunTimesOutChecksIgnoringContext(t *testing.T) {
+	release := make(chan struct{})
+	Register("test-stuck", func(context.Context) error {
+		<-release
+		return nil
+	}, Liveness)
+	t.Cleanup(func() {
+		close(release)
+		Unregister("test-stuck")
+	})
+
+	start := time.Now()
+	report := Run(context.Background(), Liveness)
+
+	if elapsed := time.Since(start); elapsed > 2*checkTimeout {
+		t.Errorf("Run took %v, want about %v", elapsed, checkTimeout)
+	}
+	if report.Healthy() {
+		t.Fatalf("report is healthy with a stuck check: %+v", report)
+	}
+	if got := report.Checks[0].Error; got != context.DeadlineExceeded.Error() {
+		t.Errorf("error = %q, want %q", got, context.DeadlineExceeded.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/config"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// AdminAuth is a middleware that protects the admin API with a bearer token.
+// When the admin token is configured (ADMIN_TOKEN), requests must carry an
+// "Authorization: Bearer <token>" header or they are rejected with 401.
+//
+// AdminAuth fails closed: when no token is configured, the admin API is
+// disabled and every request gets 404, since it can inject panics and
+// hijack connections. Setting ADMIN_INSECURE=true serves it without
+// authentication instead, for local development.
+func AdminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.Current().Admin
+		token := cfg.Token
+		if token == "" {
+			if cfg.Insecure {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.FromContext(r.Context()).Warn().
+				Str("path", r.URL.Path).
+				Str("method", r.Method).
+				Str("remote_addr", r.RemoteAddr).
+				Msg("Rejected admin request: no admin token configured")
+
+			response.SendError(w, r, http.StatusNotFound, "admin API is disabled; set ADMIN_TOKEN to enable it")
+			return
+		}
+
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			logger.FromContext(r.Context()).Warn().
+				Str("path", r.URL.Path).
+				Str("method", r.Method).
+				Str("remote_addr", r.RemoteAddr).
+				Msg("Rejected unauthorized admin request")
+
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			response.SendError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moabdelazem/go-gitops-app/internal/fault"
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// slowBodyChunkSize is the number of bytes written per chunk by the
+// slow_body fault.
+const slowBodyChunkSize = 16
+
+// chaosExemptPrefix marks routes that are never subject to fault rules, so
+// the admin API used to remove a misbehaving rule always stays reachable.
+const chaosExemptPrefix = "/admin/"
+
+// Chaos returns a middleware that applies runtime-configurable fault rules from
+// the fault package to matching requests. Rules are managed through the
+// admin API or loaded from a file, so game days don't require redeploys.
+//
+// Supported faults:
+//   - delay: Wait before calling the handler
+//   - abort: Respond with the rule's status without calling the handler
+//   - panic: Panic before calling the handler (exercises Recovery)
+//   - hijack: Close the connection without sending a response
+//   - slow_body: Trickle the response body out in small, delayed chunks
+//
+// Chaos should be registered after Recovery so injected panics are recovered.
+// Requests under /admin/ are never affected. Every injected fault is logged,
+// counted in injected_faults_total through rec and flagged with the
+// X-Fault-Injected header.
+func Chaos(rec metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, chaosExemptPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rule, ok := fault.Match(r.Method, r.URL.Path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			code := ""
+			if rule.Action == fault.ActionAbort {
+				code = strconv.Itoa(rule.Status)
+			}
+			rec.TrackInjectedFault(string(rule.Action), code)
+
+			logger.FromContext(r.Context()).Info().
+				Str("rule_id", rule.ID).
+				Str("action", string(rule.Action)).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Msg("Injecting chaos fault")
+
+			switch rule.Action {
+			case fault.ActionDelay:
+				if err := fault.Sleep(r.Context(), time.Duration(rule.Delay)); err != nil {
+					return
+				}
+				w.Header().Set(fault.Header, string(rule.Action))
+				next.ServeHTTP(w, r)
+
+			case fault.ActionAbort:
+				w.Header().Set(fault.Header, string(rule.Action))
+				response.SendError(w, r, rule.Status, "injected fault: "+http.StatusText(rule.Status))
+
+			case fault.ActionPanic:
+				panic(fmt.Sprintf("chaos: injected panic from rule %s", rule.ID))
+
+			case fault.ActionHijack:
+				conn, _, err := http.NewResponseController(w).Hijack()
+				if err != nil {
+					// HTTP/2 and some wrappers cannot be hijacked; fail loudly instead
+					logger.FromContext(r.Context()).Warn().
+						Err(err).
+						Str("rule_id", rule.ID).
+						Msg("Connection hijack not supported, aborting request instead")
+
+					w.Header().Set(fault.Header, string(rule.Action))
+					response.SendError(w, r, http.StatusBadGateway, "injected fault: connection reset")
+					return
+				}
+				_ = conn.Close()
+
+			case fault.ActionSlowBody:
+				w.Header().Set(fault.Header, string(rule.Action))
+				next.ServeHTTP(&slowBodyWriter{
+					ResponseWriter: w,
+					ctx:            r.Context(),
+					delay:          time.Duration(rule.Delay),
+				}, r)
+			}
+		})
+	}
+}
+
+// slowBodyWriter wraps http.ResponseWriter to write the body in small chunks,
+// flushing each chunk and pausing between them.
+type slowBodyWriter struct {
+	http.ResponseWriter
+	ctx   context.Context
+	delay time.Duration
+}
+
+// Write splits p into chunks of slowBodyChunkSize bytes and writes them one
+// at a time. It stops early if the client goes away.
+func (sw *slowBodyWriter) Write(p []byte) (int, error) {
+	controller := http.NewResponseController(sw.ResponseWriter)
+
+	written := 0
+	for len(p) > 0 {
+		n, err := sw.ResponseWriter.Write(p[:min(len(p), slowBodyChunkSize)])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		_ = controller.Flush()
+
+		p = p[n:]
+		if len(p) > 0 {
+			if err := fault.Sleep(sw.ctx, sw.delay); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (sw *slowBodyWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
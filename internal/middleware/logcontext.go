@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
+)
+
+// LogContext is a middleware that stores a request-scoped logger in the
+// request context, seeded with the request ID, the mux route template and
+// the client IP. Handlers and later middleware log through it with
+// logger.FromContext(r.Context()), so every line written for a request can
+// be found by its request ID.
+//
+// LogContext must run after RequestID. Like Logging, it must also wrap the
+// router's NotFoundHandler and MethodNotAllowedHandler.
+func LogContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := logger.With().
+			Str("request_id", requestid.FromContext(r.Context())).
+			Str("route", routeTemplate(r)).
+			Str("client_ip", clientIP(r)).
+			Logger()
+
+		next.ServeHTTP(w, r.WithContext(logger.WithContext(r.Context(), l)))
+	})
+}
+
+// clientIP returns the IP address of the client that sent r, without the
+// port. Forwarding headers are ignored since they can be set by anyone.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
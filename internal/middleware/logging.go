@@ -7,104 +7,161 @@
 // Example usage:
 //
 //	router := mux.NewRouter()
-//	router.Use(middleware.Logging)
+//	router.Use(middleware.Logging(recorder))
 package middleware
 
 import (
 	"net/http"
 	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/moabdelazem/go-gitops-app/pkg/logger"
 	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
 )
 
-// responseWriter wraps http.ResponseWriter to capture the status code.
-// This is necessary because the standard ResponseWriter doesn't expose
-// the status code after WriteHeader is called.
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
+// Logging returns a middleware that logs HTTP requests with structured fields.
+// It captures the request method, path, status code, duration, time to
+// first byte (until the response header was sent), and response size.
+//
+// The middleware logs at different levels based on the HTTP status code:
+//   - 2xx, 3xx: Info level, subject to request log sampling (logger.Sampled)
+//   - 4xx: Warn level (client errors)
+//   - 5xx: Error level (server errors)
+//
+// Query parameters are logged too, as are request headers when debug logs
+// are enabled for this package, with sensitive values redacted.
+//
+// This middleware also records metrics for every request in rec: the
+// number of requests in flight, and the request count, duration, request
+// size and response size by status code. Metrics are labeled with the mux
+// route template rather than the raw path to keep cardinality bounded. The
+// request size is taken from Content-Length and is 0 when the length is
+// unknown.
+//
+// Mux does not run middleware for requests that match no route, so the
+// router's NotFoundHandler and MethodNotAllowedHandler must be wrapped in
+// Logging as well to record them.
+func Logging(rec metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			done := rec.StartRequestInFlight()
+			defer done()
+
+			// Wrap the ResponseWriter to capture status code
+			wrapped := newResponseWriter(w)
+
+			// Record the request in a deferred call, so that requests whose
+			// handler panics are still logged and counted. Such panics are
+			// passed on once recorded; by then Recovery has turned every other
+			// panic into a 500, so these are responses aborted on purpose with
+			// http.ErrAbortHandler.
+			aborted := false
+			defer func() {
+				if err := recover(); err != nil {
+					aborted = true
+					defer panic(err)
+				}
+				logRequest(rec, r, wrapped, time.Since(start), aborted)
+			}()
 
-// newResponseWriter creates a new responseWriter with a default status of 200.
-func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{
-		ResponseWriter: w,
-		statusCode:     http.StatusOK,
+			// Process the request
+			next.ServeHTTP(wrapped, r)
+		})
 	}
 }
 
-// WriteHeader captures the status code before delegating to the underlying writer.
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
+// logRequest records a request served by Logging in metrics and logs it.
+// aborted reports that the handler aborted the response by panicking.
+func logRequest(rec metrics.Recorder, r *http.Request, wrapped *responseWriter, duration time.Duration, aborted bool) {
+	durationSeconds := duration.Seconds()
+
+	// Record the request in metrics under its route template
+	route := routeTemplate(r)
+	rec.TrackRequest(route, r.Method, wrapped.statusCode)
+	rec.ObserveRequestDuration(route, r.Method, wrapped.statusCode, durationSeconds, exemplarLabels(r))
+	rec.ObserveRequestSizes(route, r.Method, wrapped.statusCode, max(r.ContentLength, 0), wrapped.bytesWritten)
+
+	// Build the log event on the request logger, which already
+	// carries the request ID and route (see LogContext). Successful
+	// requests are subject to request log sampling.
+	log := logger.FromContext(r.Context())
+	var logEvent *zerolog.Event
+	switch {
+	case aborted:
+		logEvent = log.Error()
+	case wrapped.statusCode == response.StatusClientClosedRequest:
+		// The client gave up; nothing went wrong on our side
+		logEvent = log.Info()
+	case wrapped.statusCode >= 500:
+		logEvent = log.Error()
+	case wrapped.statusCode >= 400:
+		logEvent = log.Warn()
+	default:
+		logEvent = logger.Sampled(log).Info()
+	}
+
+	// Query parameters and, at debug level, headers are logged with
+	// sensitive values redacted
+	if r.URL.RawQuery != "" {
+		logEvent.Object("query", logger.Query(r.URL.Query()))
+	}
+	if log.GetLevel() <= zerolog.DebugLevel {
+		logEvent.Object("headers", logger.Headers(r.Header))
+	}
+
+	// Log the request with structured fields
+	logEvent.
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Int("status", wrapped.statusCode).
+		Dur("duration", duration).
+		Dur("ttfb", wrapped.timeToFirstByte).
+		Bool("hijacked", wrapped.hijacked).
+		Bool("aborted", aborted).
+		Int64("response_bytes", wrapped.bytesWritten).
+		Str("remote_addr", r.RemoteAddr).
+		Str("user_agent", r.UserAgent()).
+		Msg("HTTP request completed")
 }
 
-// Logging is a middleware that logs HTTP requests with structured fields.
-// It captures the request method, path, status code, and duration.
-//
-// The middleware logs at different levels based on the HTTP status code:
-//   - 2xx, 3xx: Info level
-//   - 4xx: Warn level (client errors)
-//   - 5xx: Error level (server errors)
-//
-// This middleware also records request duration in Prometheus metrics.
-func Logging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		// Wrap the ResponseWriter to capture status code
-		wrapped := newResponseWriter(w)
-
-		// Process the request
-		next.ServeHTTP(wrapped, r)
-
-		// Calculate request duration
-		duration := time.Since(start)
-		durationSeconds := duration.Seconds()
-
-		// Record duration in metrics
-		metrics.ObserveRequestDuration(r.URL.Path, r.Method, durationSeconds)
-
-		// Build the log event with common fields
-		logEvent := logger.Info()
-		if wrapped.statusCode >= 400 && wrapped.statusCode < 500 {
-			logEvent = logger.Warn()
-		} else if wrapped.statusCode >= 500 {
-			logEvent = logger.Error()
-		}
-
-		// Log the request with structured fields
-		logEvent.
-			Str("method", r.Method).
-			Str("path", r.URL.Path).
-			Int("status", wrapped.statusCode).
-			Dur("duration", duration).
-			Str("remote_addr", r.RemoteAddr).
-			Str("user_agent", r.UserAgent()).
-			Msg("HTTP request completed")
-	})
+// routeTemplate returns the path template of the mux route matched by r,
+// such as "/stress/jobs/{id}", or metrics.UnmatchedRoute if no route matched.
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return metrics.UnmatchedRoute
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return metrics.UnmatchedRoute
+	}
+	return template
 }
 
-// Recovery is a middleware that recovers from panics and returns a 500 error.
-// This prevents the server from crashing due to unhandled panics in handlers.
-//
-// When a panic is recovered, the error is logged with stack trace information
-// and a generic error response is sent to the client.
-func Recovery(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				logger.Error().
-					Interface("panic", err).
-					Str("path", r.URL.Path).
-					Str("method", r.Method).
-					Msg("Recovered from panic")
-
-				http.Error(w, `{"status":"error","message":"Internal server error"}`, http.StatusInternalServerError)
-			}
-		}()
-
-		next.ServeHTTP(w, r)
-	})
+// maxExemplarIDLength bounds request IDs used in exemplars. Prometheus
+// rejects exemplars whose labels exceed 128 characters in total, while
+// clients may send request IDs of up to requestid.MaxLength characters.
+const maxExemplarIDLength = 64
+
+// exemplarLabels returns the labels identifying r in a request duration
+// exemplar: the trace ID of the sampled span in the request context (see
+// Tracing) if there is one, otherwise the request ID (see RequestID). It
+// returns nil if r carries neither.
+func exemplarLabels(r *http.Request) map[string]string {
+	if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsSampled() {
+		return map[string]string{"trace_id": spanContext.TraceID().String()}
+	}
+
+	if id := requestid.FromContext(r.Context()); id != "" && len(id) <= maxExemplarIDLength {
+		return map[string]string{"request_id": id}
+	}
+	return nil
 }
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+// Recovery returns a middleware that recovers from panics in later handlers,
+// so a single failing request cannot crash the server.
+//
+// A recovered panic is logged at error level through the request logger,
+// with the panic value and stack trace, and counted in rec by route. If the
+// handler has not started its response yet, the client gets a 500 JSON error
+// from pkg/response carrying the request and trace IDs; headers the handler
+// had set are discarded. If the response has already started, its status
+// can no longer be changed, so the connection is aborted instead and the
+// client sees a truncated response rather than a seemingly successful one.
+//
+// Panics with http.ErrAbortHandler deliberately abort a response. They are
+// passed on to net/http, which closes the connection without logging them.
+//
+// Recovery must run after Logging and Tracing, so that recovered requests
+// are still logged, counted and traced with their 500 status. Logging also
+// records the responses Recovery aborts, flagged with "aborted".
+func Recovery(rec metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := newResponseWriter(w)
+
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if abort, ok := err.(error); ok && errors.Is(abort, http.ErrAbortHandler) {
+					panic(err)
+				}
+
+				rec.TrackPanic(routeTemplate(r))
+
+				logger.FromContext(r.Context()).Error().
+					Interface("panic", err).
+					Str("stack", string(debug.Stack())).
+					Str("method", r.Method).
+					Str("path", r.URL.Path).
+					Bool("response_started", wrapped.wroteHeader).
+					Msg("Recovered from panic")
+
+				if wrapped.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+
+				resetHeaders(wrapped.Header())
+				response.SendError(wrapped, r, http.StatusInternalServerError, "Internal server error")
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
+
+// resetHeaders removes all headers set by a failed handler except the
+// request and trace IDs, which identify the failure to the client.
+func resetHeaders(h http.Header) {
+	keep := []string{
+		http.CanonicalHeaderKey(requestid.Header),
+		http.CanonicalHeaderKey(response.TraceIDHeader),
+	}
+
+	for key := range h {
+		if key != keep[0] && key != keep[1] {
+			h.Del(key)
+		}
+	}
+}
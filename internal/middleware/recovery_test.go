@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
+)
+
+// panicRecorder is a Recorder counting tracked panics.
+type panicRecorder struct {
+	metrics.Recorder
+	panics int
+}
+
+func (p *panicRecorder) TrackPanic(string) {
+	p.panics++
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantAbort  bool
+		wantPanics int
+	}{
+		{
+			name: "panic before the response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Partial", "yes")
+				panic("boom")
+			},
+			wantPanics: 1,
+		},
+		{
+			name: "panic after the header was written",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("partial"))
+				panic("boom")
+			},
+			wantAbort:  true,
+			wantPanics: 1,
+		},
+		{
+			name: "deliberate abort",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+			wantAbort: true,
+		},
+		{
+			name:    "no panic",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &panicRecorder{Recorder: metrics.Nop()}
+			handler := RequestID(Recovery(rec)(tt.handler))
+			w := httptest.NewRecorder()
+
+			var aborted any
+			func() {
+				defer func() { aborted = recover() }()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+
+			if tt.wantAbort {
+				if err, ok := aborted.(error); !ok || !errors.Is(err, http.ErrAbortHandler) {
+					t.Errorf("panicked with %v, want http.ErrAbortHandler", aborted)
+				}
+			} else if aborted != nil {
+				t.Errorf("panic %v escaped Recovery", aborted)
+			}
+			if rec.panics != tt.wantPanics {
+				t.Errorf("tracked %d panics, want %d", rec.panics, tt.wantPanics)
+			}
+		})
+	}
+}
+
+func TestRecoveryErrorResponse(t *testing.T) {
+	handler := RequestID(Recovery(metrics.Nop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Partial", "yes")
+		w.Header().Set("Content-Type", "text/plain")
+		panic("boom")
+	})))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.Header, "panic-test")
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if w.Header().Get("X-Partial") != "" {
+		t.Error("headers set by the failed handler were kept")
+	}
+	if got := w.Header().Get(requestid.Header); got != "panic-test" {
+		t.Errorf("%s = %q, want panic-test", requestid.Header, got)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var body struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.RequestID != "panic-test" {
+		t.Errorf("body = %s, want the request ID in the error envelope", w.Body)
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
+)
+
+// RequestID is a middleware that assigns every request a correlation ID.
+// It reuses the client's X-Request-ID header if it is valid (see
+// requestid.Valid) and otherwise generates a new ID, so clients such as k6
+// can tie a failed request to its log lines.
+//
+// The ID is stored in the request context, where LogContext adds it to the
+// request logger as request_id, and echoed in the X-Request-ID response
+// header, which response.Send and response.SendError copy into the
+// response body.
+//
+// RequestID should run before all other middleware so that their logs carry
+// the ID too. Like Logging, it must also wrap the router's NotFoundHandler
+// and MethodNotAllowedHandler to cover requests that match no route.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
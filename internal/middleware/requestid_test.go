@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientID string
+		reused   bool
+	}{
+		{name: "client ID is reused", clientID: "k6-vu3-iter17", reused: true},
+		{name: "missing ID is generated"},
+		{name: "ID with spaces is replaced", clientID: "not valid"},
+		{name: "overlong ID is replaced", clientID: strings.Repeat("a", requestid.MaxLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextID string
+			var logLine bytes.Buffer
+			handler := RequestID(LogContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID = requestid.FromContext(r.Context())
+				l := logger.FromContext(r.Context()).Output(&logLine)
+				l.Info().Msg("handled")
+				response.Send(w, r, http.StatusOK, response.Success("ok"))
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.clientID != "" {
+				req.Header.Set(requestid.Header, tt.clientID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(requestid.Header)
+			switch {
+			case tt.reused && id != tt.clientID:
+				t.Errorf("%s = %q, want the client's %q", requestid.Header, id, tt.clientID)
+			case !tt.reused && (id == tt.clientID || !requestid.Valid(id) || len(id) != 32):
+				t.Errorf("%s = %q, want a new 32 character ID", requestid.Header, id)
+			}
+			if contextID != id {
+				t.Errorf("context ID = %q, want %q", contextID, id)
+			}
+
+			var line struct {
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(logLine.Bytes(), &line); err != nil || line.RequestID != id {
+				t.Errorf("log line %q, want request_id %q", logLine.String(), id)
+			}
+
+			var body response.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.RequestID != id {
+				t.Errorf("body %s, want request_id %q", w.Body, id)
+			}
+		})
+	}
+}
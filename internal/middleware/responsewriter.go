@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// responseWriter wraps http.ResponseWriter to capture the status code, the
+// number of body bytes written, whether and when the header was sent, and
+// whether the connection was hijacked. This is necessary because the
+// standard ResponseWriter doesn't expose any of these after the response is
+// written.
+//
+// The wrapper keeps the optional interfaces of the writer it wraps usable:
+// it implements http.Flusher, http.Hijacker, http.Pusher and io.ReaderFrom
+// by delegating to the underlying writer, returning http.ErrNotSupported (or
+// falling back to plain writes for io.ReaderFrom) when that writer lacks
+// them. Unwrap lets http.ResponseController reach any other features.
+type responseWriter struct {
+	http.ResponseWriter
+
+	// statusCode is the final status sent, 200 if the handler wrote the
+	// body without calling WriteHeader.
+	statusCode int
+
+	// bytesWritten counts the body bytes written.
+	bytesWritten int64
+
+	// wroteHeader reports whether the final header has been sent. Once it
+	// has, the status can no longer change.
+	wroteHeader bool
+
+	// hijacked reports whether the handler took over the connection.
+	hijacked bool
+
+	// start is when the wrapper was created, and timeToFirstByte the time
+	// from start until the final header was sent.
+	start           time.Time
+	timeToFirstByte time.Duration
+}
+
+// newResponseWriter creates a new responseWriter with a default status of 200.
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+		start:          time.Now(),
+	}
+}
+
+// WriteHeader captures the status code before delegating to the underlying
+// writer. Informational (1xx) statuses other than 101 Switching Protocols
+// may be sent any number of times before the final one and are passed on
+// without being recorded. Calls after the final header has been sent are
+// ignored, as net/http would ignore them too.
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		rw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	rw.markHeaderWritten(code)
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// markHeaderWritten records that the final header with status code has
+// been sent.
+func (rw *responseWriter) markHeaderWritten(code int) {
+	rw.statusCode = code
+	rw.wroteHeader = true
+	rw.timeToFirstByte = time.Since(rw.start)
+}
+
+// Write counts the body bytes before delegating to the underlying writer.
+// Writing before WriteHeader sends an implicit 200 status.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.markHeaderWritten(http.StatusOK)
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += int64(n)
+	return n, err
+}
+
+// ReadFrom copies src to the response, letting the underlying writer use
+// an optimized path such as sendfile when it implements io.ReaderFrom.
+// Like Write, it sends an implicit 200 status first.
+func (rw *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !rw.wroteHeader {
+		rw.markHeaderWritten(http.StatusOK)
+	}
+
+	var n int64
+	var err error
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		// Hide ReadFrom from io.Copy, which would otherwise call it again
+		n, err = io.Copy(struct{ io.Writer }{rw.ResponseWriter}, src)
+	}
+	rw.bytesWritten += n
+	return n, err
+}
+
+// Flush implements http.Flusher. Flushing before WriteHeader sends an
+// implicit 200 status, as in net/http.
+func (rw *responseWriter) Flush() {
+	_ = rw.FlushError()
+}
+
+// FlushError flushes buffered data to the client and reports any error,
+// for http.ResponseController. It returns http.ErrNotSupported if the
+// underlying writer cannot flush.
+func (rw *responseWriter) FlushError() error {
+	if !rw.wroteHeader {
+		rw.markHeaderWritten(http.StatusOK)
+	}
+	return http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker. It returns http.ErrNotSupported if the
+// underlying writer cannot be hijacked, such as on HTTP/2 connections.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.hijacked = true
+	}
+	return conn, buf, err
+}
+
+// Push implements http.Pusher. It returns http.ErrNotSupported if the
+// underlying writer does not support HTTP/2 server push.
+func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter so http.ResponseController can
+// reach optional interfaces such as deadlines and full-duplex mode.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
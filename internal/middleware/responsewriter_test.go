@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fullWriter is a ResponseWriter implementing every optional interface the
+// responseWriter delegates to, recording which of them were called.
+type fullWriter struct {
+	*httptest.ResponseRecorder
+
+	hijacked, readFrom bool
+	pushed             string
+}
+
+func (f *fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	f.hijacked = true
+	server, client := net.Pipe()
+	_ = client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func (f *fullWriter) ReadFrom(src io.Reader) (int64, error) {
+	f.readFrom = true
+	return io.Copy(f.ResponseRecorder.Body, src)
+}
+
+func (f *fullWriter) Push(target string, _ *http.PushOptions) error {
+	f.pushed = target
+	return nil
+}
+
+// onlyReader returns a reader of s without WriteTo, so io.Copy uses the
+// ReadFrom method of the destination.
+func onlyReader(s string) io.Reader {
+	return struct{ io.Reader }{strings.NewReader(s)}
+}
+
+// plainWriter hides every optional interface of the writer it wraps.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+// outerWriter stands for another middleware's wrapper, reachable by
+// http.ResponseController only through Unwrap.
+type outerWriter struct {
+	http.ResponseWriter
+}
+
+func (o outerWriter) Unwrap() http.ResponseWriter {
+	return o.ResponseWriter
+}
+
+func TestResponseWriterStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		write      func(w http.ResponseWriter)
+		wantStatus int
+		wantBytes  int64
+		wantHeader bool
+
+		// interim is set when the underlying recorder keeps an
+		// informational status instead of the final one
+		interim bool
+	}{
+		{
+			name:       "nothing written",
+			write:      func(http.ResponseWriter) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "implicit 200",
+			write:      func(w http.ResponseWriter) { _, _ = w.Write([]byte("hello")) },
+			wantStatus: http.StatusOK,
+			wantBytes:  5,
+			wantHeader: true,
+		},
+		{
+			name: "double WriteHeader keeps the first status",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte("ok"))
+			},
+			wantStatus: http.StatusCreated,
+			wantBytes:  2,
+			wantHeader: true,
+		},
+		{
+			name: "informational status is passed on",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusAccepted)
+			},
+			wantStatus: http.StatusAccepted,
+			wantHeader: true,
+			interim:    true,
+		},
+		{
+			name:       "ReadFrom counts bytes",
+			write:      func(w http.ResponseWriter) { _, _ = io.Copy(w, onlyReader("streamed")) },
+			wantStatus: http.StatusOK,
+			wantBytes:  8,
+			wantHeader: true,
+		},
+		{
+			name:       "flush sends the header",
+			write:      func(w http.ResponseWriter) { w.(http.Flusher).Flush() },
+			wantStatus: http.StatusOK,
+			wantHeader: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rw := newResponseWriter(rec)
+			tt.write(rw)
+
+			if rw.statusCode != tt.wantStatus || rw.bytesWritten != tt.wantBytes || rw.wroteHeader != tt.wantHeader {
+				t.Errorf("status, bytes, wroteHeader = %d, %d, %v; want %d, %d, %v",
+					rw.statusCode, rw.bytesWritten, rw.wroteHeader, tt.wantStatus, tt.wantBytes, tt.wantHeader)
+			}
+			if tt.wantHeader && !tt.interim && rec.Code != tt.wantStatus {
+				t.Errorf("underlying status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestResponseWriterTimeToFirstByte(t *testing.T) {
+	rw := newResponseWriter(httptest.NewRecorder())
+
+	time.Sleep(10 * time.Millisecond)
+	rw.WriteHeader(http.StatusNoContent)
+	ttfb := rw.timeToFirstByte
+
+	time.Sleep(10 * time.Millisecond)
+	_, _ = rw.Write(nil)
+
+	if ttfb < 10*time.Millisecond {
+		t.Errorf("timeToFirstByte = %v, want at least 10ms", ttfb)
+	}
+	if rw.timeToFirstByte != ttfb {
+		t.Errorf("timeToFirstByte changed from %v to %v after the header was sent", ttfb, rw.timeToFirstByte)
+	}
+}
+
+func TestResponseWriterOptionalInterfaces(t *testing.T) {
+	full := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	rw := newResponseWriter(full)
+	rc := http.NewResponseController(outerWriter{rw})
+
+	if err := rc.Flush(); err != nil || !full.Flushed {
+		t.Errorf("Flush() = %v, flushed = %v; want the underlying writer flushed", err, full.Flushed)
+	}
+
+	conn, _, err := rc.Hijack()
+	if err != nil || !full.hijacked || !rw.hijacked {
+		t.Errorf("Hijack() = %v; want the underlying connection, recorded as hijacked", err)
+	}
+	if conn != nil {
+		_ = conn.Close()
+	}
+
+	if _, err := io.Copy(rw, onlyReader("body")); err != nil || !full.readFrom {
+		t.Errorf("io.Copy() = %v, used ReadFrom = %v; want the underlying ReadFrom", err, full.readFrom)
+	}
+
+	var pusher http.Pusher = rw
+	if err := pusher.Push("/style.css", nil); err != nil || full.pushed != "/style.css" {
+		t.Errorf("Push() = %v, pushed %q; want /style.css pushed", err, full.pushed)
+	}
+}
+
+func TestResponseWriterUnsupportedInterfaces(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := newResponseWriter(plainWriter{rec})
+	rc := http.NewResponseController(outerWriter{rw})
+
+	if err := rc.Flush(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Flush() = %v, want http.ErrNotSupported", err)
+	}
+	if _, _, err := rc.Hijack(); !errors.Is(err, http.ErrNotSupported) || rw.hijacked {
+		t.Errorf("Hijack() = %v, hijacked = %v; want http.ErrNotSupported", err, rw.hijacked)
+	}
+	if err := rw.Push("/style.css", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Push() = %v, want http.ErrNotSupported", err)
+	}
+
+	// ReadFrom falls back to plain writes
+	if n, err := io.Copy(rw, onlyReader("body")); err != nil || n != 4 || rec.Body.String() != "body" {
+		t.Errorf("io.Copy() = %d, %v, body %q; want the body written", n, err, rec.Body)
+	}
+}
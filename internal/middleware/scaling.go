@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/metrics"
+)
+
+// scalingExemptPaths are infrastructure endpoints excluded from the scaling
+// signals. Probes and scrapes arrive at a fixed rate regardless of load and
+// would otherwise keep every pod looking busy.
+var scalingExemptPaths = map[string]bool{
+	"/health":   true,
+	"/livez":    true,
+	"/readyz":   true,
+	"/startupz": true,
+	"/metrics":  true,
+	"/version":  true,
+}
+
+// Scaling returns a middleware that records application requests in the
+// scaling signals of rec (in-flight requests and request rate) used by the
+// HPA through Prometheus or the custom metrics API. Probe, metrics, version
+// and admin requests are not counted.
+func Scaling(rec metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scalingExemptPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, "/admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			done := rec.StartScalingRequest()
+			defer done()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
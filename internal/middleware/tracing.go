@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/response"
+	"github.com/moabdelazem/go-gitops-app/pkg/tracing"
+)
+
+// Tracing is a middleware that starts a server span for every request,
+// continuing the trace from a W3C traceparent header if the client sent one.
+// The span is named after the method and mux route template, and the request
+// context passed on carries it, so child spans and log events given that
+// context join the same trace.
+//
+// The trace ID is echoed in the X-Trace-ID response header, which
+// response.Send and response.SendError copy into the response body.
+// Responses with a 5xx status mark the span as failed.
+//
+// Like Logging, Tracing must also wrap the router's NotFoundHandler and
+// MethodNotAllowedHandler to cover requests that match no route.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := routeTemplate(r)
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", r.RemoteAddr),
+				attribute.String("user_agent.original", r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		// Mark the span failed if a panic gets past Recovery, such as
+		// http.ErrAbortHandler aborting the response
+		defer func() {
+			if err := recover(); err != nil {
+				span.SetStatus(codes.Error, fmt.Sprint("panic: ", err))
+				panic(err)
+			}
+		}()
+
+		if spanContext := span.SpanContext(); spanContext.HasTraceID() {
+			w.Header().Set(response.TraceIDHeader, spanContext.TraceID().String())
+		}
+
+		wrapped := newResponseWriter(w)
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.statusCode))
+		if wrapped.statusCode >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+		}
+	})
+}
@@ -0,0 +1,521 @@
+// Package config provides the application's typed configuration.
+//
+// Configuration is assembled from several sources. Later sources override
+// earlier ones:
+//
+//  1. Built-in defaults (see Default)
+//  2. A YAML or JSON file named by the CONFIG_FILE environment variable
+//  3. The optional .env file in the working directory
+//  4. Process environment variables
+//
+// Variables from the .env file never override variables that are already
+// set in the process environment. The assembled configuration is validated
+// with go-playground/validator, and Load fails on any invalid value so that
+// misconfiguration is caught at startup rather than at first use.
+//
+// Calling Load and Set again replaces the active configuration at runtime.
+// WatchFiles detects changes to a mounted config file, including Kubernetes
+// ConfigMap updates, so callers can reload without a restart. Code that reads
+// Current on each use picks up the new values immediately.
+//
+// Example usage:
+//
+//	cfg, err := config.Load()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	config.Set(cfg)
+//	timeout := config.Current().Server.ShutdownTimeout
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"go.yaml.in/yaml/v2"
+)
+
+// FileEnv names the environment variable holding the optional config file path.
+const FileEnv = "CONFIG_FILE"
+
+// redacted replaces the value of secret fields in Redacted output.
+const redacted = "[REDACTED]"
+
+// Config is the complete application configuration.
+//
+// Each field carries a yaml tag naming its key in the config file and, where
+// applicable, an env tag naming the environment variable that overrides it.
+// Fields tagged secret:"true" are redacted when the configuration is exposed.
+type Config struct {
+	Server ServerConfig `yaml:"server"`
+	Log    LogConfig    `yaml:"log"`
+	Stress StressConfig `yaml:"stress"`
+	Fault  FaultConfig  `yaml:"fault"`
+	Admin  AdminConfig  `yaml:"admin"`
+
+	Metrics       MetricsConfig       `yaml:"metrics"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+	CustomMetrics CustomMetricsConfig `yaml:"custom_metrics"`
+}
+
+// ServerConfig configures the HTTP server and its shutdown behavior.
+type ServerConfig struct {
+	// Port is the HTTP server port.
+	Port int `yaml:"port" env:"PORT" validate:"min=1,max=65535"`
+
+	// ShutdownPreStopDelay is the delay between failing readiness and
+	// draining connections during shutdown.
+	ShutdownPreStopDelay time.Duration `yaml:"shutdown_pre_stop_delay" env:"SHUTDOWN_PRE_STOP_DELAY" validate:"min=0s,max=5m"`
+
+	// ShutdownTimeout is the maximum time to drain in-flight requests.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" validate:"min=1s,max=5m"`
+}
+
+// LogConfig configures the logger.
+type LogConfig struct {
+	// Level is the minimum log level: debug, info, warn or error.
+	Level string `yaml:"level" env:"LOG_LEVEL" validate:"oneof=debug info warn warning error"`
+
+	// Format is json for structured logs or console for colored,
+	// human-readable logs during local development.
+	Format string `yaml:"format" env:"LOG_FORMAT" validate:"oneof=json console"`
+
+	// File, when set, also writes JSON logs to this file, rotated once it
+	// reaches FileMaxSizeMiB and keeping FileMaxBackups old files.
+	File           string `yaml:"file" env:"LOG_FILE"`
+	FileMaxSizeMiB int    `yaml:"file_max_size_mib" env:"LOG_FILE_MAX_SIZE_MIB" validate:"min=1"`
+	FileMaxBackups int    `yaml:"file_max_backups" env:"LOG_FILE_MAX_BACKUPS" validate:"min=0"`
+
+	// RequestSampleEvery logs only one in every RequestSampleEvery
+	// successful requests. Failed requests are always logged.
+	RequestSampleEvery int `yaml:"request_sample_every" env:"LOG_REQUEST_SAMPLE_EVERY" validate:"min=1"`
+
+	// RateLimit caps the log lines written per second at each level.
+	RateLimit LogRateLimitConfig `yaml:"rate_limit"`
+
+	// RedactKeys are the query parameter and header names whose values are
+	// redacted in logs. Names match if they contain a key, ignoring case.
+	// Empty uses a built-in list covering tokens, passwords and cookies.
+	RedactKeys []string `yaml:"redact_keys"`
+}
+
+// LogRateLimitConfig caps log lines per second per level. 0 means unlimited.
+type LogRateLimitConfig struct {
+	Debug int `yaml:"debug" env:"LOG_RATE_LIMIT_DEBUG" validate:"min=0"`
+	Info  int `yaml:"info" env:"LOG_RATE_LIMIT_INFO" validate:"min=0"`
+	Warn  int `yaml:"warn" env:"LOG_RATE_LIMIT_WARN" validate:"min=0"`
+	Error int `yaml:"error" env:"LOG_RATE_LIMIT_ERROR" validate:"min=0"`
+}
+
+// StressConfig configures the stress endpoints.
+type StressConfig struct {
+	// DefaultDuration is used when a CPU stress request has no duration.
+	DefaultDuration time.Duration `yaml:"default_duration" env:"STRESS_DEFAULT_DURATION" validate:"min=1s,ltefield=MaxDuration"`
+
+	// MaxDuration is the longest CPU stress run a request may ask for.
+	MaxDuration time.Duration `yaml:"max_duration" env:"STRESS_MAX_DURATION" validate:"min=1s,max=10m"`
+
+	// SaturationWorkers is the number of running stress workers at which
+	// the readiness probe starts failing.
+	SaturationWorkers int `yaml:"saturation_workers" env:"STRESS_SATURATION_WORKERS" validate:"min=1"`
+
+	// MaxRunningJobs caps concurrent background stress jobs.
+	MaxRunningJobs int `yaml:"max_running_jobs" env:"STRESS_MAX_RUNNING_JOBS" validate:"min=1,max=1024"`
+
+	// MaxMemoryMiB caps memory stress allocations when no cgroup limit applies.
+	MaxMemoryMiB int `yaml:"max_memory_mib" env:"STRESS_MAX_MEMORY_MIB" validate:"min=1"`
+}
+
+// FaultConfig configures chaos fault injection.
+type FaultConfig struct {
+	// RulesFile is an optional JSON file of fault rules loaded at startup.
+	RulesFile string `yaml:"rules_file" env:"FAULT_RULES_FILE"`
+}
+
+// AdminConfig configures the /admin API.
+type AdminConfig struct {
+	// Token must be presented as a bearer token on /admin requests. While
+	// it is empty, the admin API is disabled unless Insecure is set.
+	Token string `yaml:"token" env:"ADMIN_TOKEN" secret:"true"`
+
+	// Insecure serves the admin API without authentication while no Token
+	// is set. Meant for local development only.
+	Insecure bool `yaml:"insecure" env:"ADMIN_INSECURE"`
+}
+
+// MetricsConfig configures the Prometheus metrics. Bucket lists can only be
+// set in the config file.
+type MetricsConfig struct {
+	// DurationBuckets are the request duration buckets in seconds of
+	// regular routes. Empty uses the Prometheus default buckets.
+	DurationBuckets []float64 `yaml:"duration_buckets" validate:"omitempty,dive,gt=0"`
+
+	// StressDurationBuckets are the request duration buckets in seconds of
+	// the long-running routes: /stress, /stress/memory and /fault. Empty
+	// uses buckets of up to 5m.
+	StressDurationBuckets []float64 `yaml:"stress_duration_buckets" validate:"omitempty,dive,gt=0"`
+
+	// NativeHistograms additionally exposes request durations as Prometheus
+	// native histograms.
+	NativeHistograms bool `yaml:"native_histograms" env:"METRICS_NATIVE_HISTOGRAMS"`
+
+	// Exemplars attaches trace or request IDs to request duration samples.
+	Exemplars bool `yaml:"exemplars" env:"METRICS_EXEMPLARS"`
+
+	// GoCollector exposes Go runtime metrics (go_*).
+	GoCollector bool `yaml:"go_collector" env:"METRICS_GO_COLLECTOR"`
+
+	// ProcessCollector exposes process metrics (process_*).
+	ProcessCollector bool `yaml:"process_collector" env:"METRICS_PROCESS_COLLECTOR"`
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	// Exporter selects where spans are sent: none, otlp-http, otlp-grpc or
+	// stdout. Trace context is propagated even when it is none.
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" validate:"oneof=none otlp-http otlp-grpc stdout"`
+
+	// Endpoint is the OTLP collector address, such as otel-collector:4318.
+	// Empty uses OTEL_EXPORTER_OTLP_ENDPOINT or the exporter default.
+	Endpoint string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+
+	// Insecure disables TLS towards the OTLP collector.
+	Insecure bool `yaml:"insecure" env:"TRACING_INSECURE"`
+
+	// SampleRatio is the fraction of new traces that are recorded. Requests
+	// whose parent span was sampled are always recorded.
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" validate:"min=0,max=1"`
+
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" validate:"required"`
+}
+
+// CustomMetricsConfig configures the built-in Kubernetes custom metrics API
+// server (custom.metrics.k8s.io) that serves the scaling signals to the HPA.
+type CustomMetricsConfig struct {
+	// Enabled starts the custom metrics API server.
+	Enabled bool `yaml:"enabled" env:"CUSTOM_METRICS_ENABLED"`
+
+	// Port is the HTTPS port of the custom metrics API server. It must
+	// differ from server.port.
+	Port int `yaml:"port" env:"CUSTOM_METRICS_PORT" validate:"min=1,max=65535"`
+
+	// Selector is the label selector of the application pods.
+	Selector string `yaml:"selector" env:"CUSTOM_METRICS_SELECTOR" validate:"required"`
+
+	// ObjectName is the name of the application's Deployment and Service,
+	// the only objects whose Object metrics are served.
+	ObjectName string `yaml:"object_name" env:"CUSTOM_METRICS_OBJECT_NAME" validate:"required"`
+
+	// PodName is the name of this pod, usually set through the downward API.
+	// Its signals are read locally instead of over the network.
+	PodName string `yaml:"pod_name" env:"POD_NAME"`
+
+	// CertFile and KeyFile are the certificate and key the pod serves and
+	// presents to peers. They are required when Enabled and are reloaded
+	// when they change.
+	CertFile string `yaml:"cert_file" env:"CUSTOM_METRICS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"CUSTOM_METRICS_KEY_FILE"`
+
+	// CAFile holds the CA certificates that issued CertFile. Pods verify
+	// each other's certificates with them. Required when Enabled.
+	CAFile string `yaml:"ca_file" env:"CUSTOM_METRICS_CA_FILE"`
+
+	// ServerName is the name peer serving certificates are verified for,
+	// since peers are addressed by pod IP.
+	ServerName string `yaml:"server_name" env:"CUSTOM_METRICS_SERVER_NAME" validate:"required"`
+}
+
+// validate is the validator instance used for configuration.
+var validate = validator.New()
+
+// current holds the active configuration.
+var current atomic.Pointer[Config]
+
+// Default returns the built-in default configuration.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:                 8080,
+			ShutdownPreStopDelay: 5 * time.Second,
+			ShutdownTimeout:      20 * time.Second,
+		},
+		Log: LogConfig{
+			Level:              "info",
+			Format:             "json",
+			FileMaxSizeMiB:     100,
+			FileMaxBackups:     3,
+			RequestSampleEvery: 1,
+		},
+		Stress: StressConfig{
+			DefaultDuration:   2 * time.Second,
+			MaxDuration:       30 * time.Second,
+			SaturationWorkers: runtime.NumCPU() * 2,
+			MaxRunningJobs:    32,
+			MaxMemoryMiB:      2048,
+		},
+		Metrics: MetricsConfig{
+			GoCollector:      true,
+			ProcessCollector: true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "go-gitops-app",
+		},
+		CustomMetrics: CustomMetricsConfig{
+			Port:       6443,
+			Selector:   "app=go-gitops-app",
+			ObjectName: "go-gitops-app",
+			ServerName: "go-gitops-app-custom-metrics",
+		},
+	}
+}
+
+// Load assembles the configuration from defaults, the config file, the .env
+// file and the environment, then validates it. It returns an error describing
+// every problem found if any value is invalid.
+func Load() (*Config, error) {
+	// The .env file is optional; a missing file is not an error
+	_ = godotenv.Load()
+
+	cfg := Default()
+
+	if path := os.Getenv(FileEnv); path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if errs := applyEnv(reflect.ValueOf(cfg).Elem()); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid environment: %s", strings.Join(errs, "; "))
+	}
+
+	cfg.Log.Level = strings.ToLower(strings.TrimSpace(cfg.Log.Level))
+
+	if err := validate.Struct(cfg); err != nil {
+		return nil, formatValidationError(err)
+	}
+	for key, buckets := range map[string][]float64{
+		"metrics.duration_buckets":        cfg.Metrics.DurationBuckets,
+		"metrics.stress_duration_buckets": cfg.Metrics.StressDurationBuckets,
+	} {
+		if !isStrictlyIncreasing(buckets) {
+			return nil, fmt.Errorf("invalid configuration: %s must be in increasing order", key)
+		}
+	}
+	if cfg.CustomMetrics.Enabled && cfg.CustomMetrics.Port == cfg.Server.Port {
+		return nil, fmt.Errorf("invalid configuration: custom_metrics.port (CUSTOM_METRICS_PORT) must differ from server.port (PORT)")
+	}
+	if m := cfg.CustomMetrics; m.Enabled && (m.CertFile == "" || m.KeyFile == "" || m.CAFile == "") {
+		return nil, fmt.Errorf("invalid configuration: custom metrics require custom_metrics.cert_file (CUSTOM_METRICS_CERT_FILE), " +
+			"custom_metrics.key_file (CUSTOM_METRICS_KEY_FILE) and custom_metrics.ca_file (CUSTOM_METRICS_CA_FILE), " +
+			"since peer pods must verify each other")
+	}
+	return cfg, nil
+}
+
+// isStrictlyIncreasing reports whether every value is greater than the one
+// before it.
+func isStrictlyIncreasing(values []float64) bool {
+	for i := 1; i < len(values); i++ {
+		if values[i] <= values[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// Set makes cfg the active configuration returned by Current and advances
+// the configuration generation.
+func Set(cfg *Config) {
+	current.Store(cfg)
+	generation.Add(1)
+}
+
+// Current returns the active configuration. If Set has not been called, the
+// defaults are returned. The returned value must not be modified.
+func Current() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return Default()
+}
+
+// loadFile decodes a YAML or JSON config file into cfg. Since JSON is valid
+// YAML, both formats are handled by the YAML decoder. Unknown keys are
+// rejected to catch typos.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides fields of the struct v with the environment variables
+// named by their env tags, recursing into nested structs. It returns one
+// message per variable that could not be parsed.
+func applyEnv(v reflect.Value) []string {
+	t := v.Type()
+	var errs []string
+
+	for i := range t.NumField() {
+		field := v.Field(i)
+		structField := t.Field(i)
+
+		if structField.Type.Kind() == reflect.Struct {
+			errs = append(errs, applyEnv(field)...)
+			continue
+		}
+
+		key := structField.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(key)
+		if !ok || value == "" {
+			continue
+		}
+
+		if err := setField(field, value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s=%q: %v", key, value, err))
+		}
+	}
+	return errs
+}
+
+// setField parses value according to the field's type and stores it.
+func setField(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 10s")
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		field.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// formatValidationError converts validator errors into a single error that
+// names each failing setting by its config file key and environment variable.
+func formatValidationError(err error) error {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		key, env := describeField(fieldErr.StructNamespace())
+
+		name := key
+		if env != "" {
+			name += " (" + env + ")"
+		}
+
+		rule := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			rule += "=" + fieldErr.Param()
+		}
+		messages = append(messages, fmt.Sprintf("%s: value %v does not satisfy %s", name, fieldErr.Value(), rule))
+	}
+	return fmt.Errorf("invalid configuration: %s", strings.Join(messages, "; "))
+}
+
+// describeField maps a validator struct namespace such as
+// "Config.Server.Port" to its dotted config file key and env variable. List
+// elements keep their index, as in "metrics.duration_buckets[1]".
+func describeField(namespace string) (key, env string) {
+	parts := strings.Split(namespace, ".")[1:]
+
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, len(parts))
+	for _, part := range parts {
+		name, index, _ := strings.Cut(part, "[")
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return namespace, ""
+		}
+		if index != "" {
+			index = "[" + index
+		}
+		keys = append(keys, field.Tag.Get("yaml")+index)
+		env = field.Tag.Get("env")
+		t = field.Type
+	}
+	return strings.Join(keys, "."), env
+}
+
+// Redacted returns cfg as a nested map keyed by config file keys, suitable
+// for exposing the effective configuration. Durations are rendered as
+// strings and non-empty secret fields are replaced with "[REDACTED]".
+func Redacted(cfg *Config) map[string]any {
+	return redactStruct(reflect.ValueOf(cfg).Elem())
+}
+
+// redactStruct converts a struct value to a map, applying redaction.
+func redactStruct(v reflect.Value) map[string]any {
+	t := v.Type()
+	out := make(map[string]any, t.NumField())
+
+	for i := range t.NumField() {
+		field := v.Field(i)
+		structField := t.Field(i)
+		key := structField.Tag.Get("yaml")
+
+		switch {
+		case structField.Type.Kind() == reflect.Struct:
+			out[key] = redactStruct(field)
+		case structField.Tag.Get("secret") == "true":
+			if field.IsZero() {
+				out[key] = ""
+			} else {
+				out[key] = redacted
+			}
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			out[key] = time.Duration(field.Int()).String()
+		default:
+			out[key] = field.Interface()
+		}
+	}
+	return out
+}
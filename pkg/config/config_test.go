@@ -0,0 +1,288 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate runs the test in an empty working directory with CONFIG_FILE and
+// every variable named by an env tag unset, so Load sees only what the test
+// provides. Variables set by the .env file are removed when the test ends.
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	keys := []string{FileEnv}
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if field.Type.Kind() == reflect.Struct {
+				collect(field.Type)
+			} else if key := field.Tag.Get("env"); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	collect(reflect.TypeOf(Config{}))
+
+	for _, key := range keys {
+		// t.Setenv restores the original value, or unsets the variable,
+		// when the test ends
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	return dir
+}
+
+// writeFile writes content to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	isolate(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load() = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := isolate(t)
+
+	t.Setenv(FileEnv, writeFile(t, dir, "config.yaml", `
+server:
+  port: 9000
+  shutdown_timeout: 30s
+log:
+  level: debug
+  format: console
+stress:
+  max_running_jobs: 10
+`))
+	writeFile(t, dir, ".env", "PORT=9100\nLOG_LEVEL=WARN\nLOG_FORMAT=json\n")
+	t.Setenv("PORT", "9200")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "environment over .env and file", got: cfg.Server.Port, want: 9200},
+		{name: ".env over file", got: cfg.Log.Format, want: "json"},
+		{name: ".env level is normalized", got: cfg.Log.Level, want: "warn"},
+		{name: "file over defaults", got: cfg.Server.ShutdownTimeout, want: 30 * time.Second},
+		{name: "nested file value", got: cfg.Stress.MaxRunningJobs, want: 10},
+		{name: "default", got: cfg.Server.ShutdownPreStopDelay, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown key", content: "server:\n  prot: 9000\n", wantErr: "field prot not found"},
+		{name: "unknown section", content: "logging:\n  level: debug\n", wantErr: "field logging not found"},
+		{name: "duplicate key", content: "server:\n  port: 1\n  port: 2\n", wantErr: "field port already set"},
+		{name: "wrong type", content: "server:\n  port: eighty\n", wantErr: "cannot unmarshal"},
+		{name: "json", content: `{"server": {"port": 9000}, "log": {"lvl": "debug"}}`, wantErr: "field lvl not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			t.Setenv(FileEnv, writeFile(t, dir, "config.yaml", tt.content))
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), "parse config file") {
+				t.Errorf("Load() = %v, want it to name the config file", err)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		dir := isolate(t)
+		t.Setenv(FileEnv, filepath.Join(dir, "missing.yaml"))
+
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "read config file") {
+			t.Errorf("Load() = %v, want a read error", err)
+		}
+	})
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		file    string
+		wantErr []string
+	}{
+		{
+			name:    "out of range",
+			env:     map[string]string{"PORT": "0"},
+			wantErr: []string{"invalid configuration: server.port (PORT): value 0 does not satisfy min=1"},
+		},
+		{
+			name:    "not one of",
+			env:     map[string]string{"LOG_LEVEL": "verbose"},
+			wantErr: []string{"log.level (LOG_LEVEL): value verbose does not satisfy oneof=debug info warn warning error"},
+		},
+		{
+			name:    "every problem reported",
+			env:     map[string]string{"PORT": "70000", "LOG_FORMAT": "xml"},
+			wantErr: []string{"server.port (PORT): value 70000 does not satisfy max=65535; log.format (LOG_FORMAT): value xml"},
+		},
+		{
+			name:    "cross field",
+			env:     map[string]string{"STRESS_DEFAULT_DURATION": "1m"},
+			wantErr: []string{"stress.default_duration (STRESS_DEFAULT_DURATION): value 1m0s does not satisfy ltefield=MaxDuration"},
+		},
+		{
+			name:    "file only key",
+			file:    "metrics:\n  duration_buckets: [0.1, -1]\n",
+			wantErr: []string{"metrics.duration_buckets[1]: value -1 does not satisfy gt=0"},
+		},
+		{
+			name:    "unordered buckets",
+			file:    "metrics:\n  stress_duration_buckets: [10, 1]\n",
+			wantErr: []string{"metrics.stress_duration_buckets must be in increasing order"},
+		},
+		{
+			name: "unparsable environment",
+			env:  map[string]string{"PORT": "http", "ADMIN_INSECURE": "sure", "SHUTDOWN_TIMEOUT": "20"},
+			wantErr: []string{
+				`invalid environment: `,
+				`PORT="http": must be an integer`,
+				`ADMIN_INSECURE="sure": must be true or false`,
+				`SHUTDOWN_TIMEOUT="20": must be a duration such as 10s`,
+			},
+		},
+		{
+			name: "custom metrics on the server port",
+			env: map[string]string{
+				"CUSTOM_METRICS_ENABLED": "true", "CUSTOM_METRICS_PORT": "8080",
+				"CUSTOM_METRICS_CERT_FILE": "tls.crt", "CUSTOM_METRICS_KEY_FILE": "tls.key", "CUSTOM_METRICS_CA_FILE": "ca.crt",
+			},
+			wantErr: []string{"custom_metrics.port (CUSTOM_METRICS_PORT) must differ from server.port (PORT)"},
+		},
+		{
+			name:    "custom metrics without certificates",
+			env:     map[string]string{"CUSTOM_METRICS_ENABLED": "true", "CUSTOM_METRICS_CERT_FILE": "tls.crt"},
+			wantErr: []string{"custom metrics require custom_metrics.cert_file", "custom_metrics.ca_file (CUSTOM_METRICS_CA_FILE)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			if tt.file != "" {
+				t.Setenv(FileEnv, writeFile(t, dir, "config.yaml", tt.file))
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Load()
+			if err == nil {
+				t.Fatal("Load() succeeded, want a validation error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Admin.Token = "s3cret"
+	cfg.Log.RedactKeys = []string{"ssn"}
+
+	out := Redacted(cfg)
+
+	admin := out["admin"].(map[string]any)
+	if admin["token"] != "[REDACTED]" {
+		t.Errorf("admin.token = %v, want [REDACTED]", admin["token"])
+	}
+	if admin["insecure"] != false {
+		t.Errorf("admin.insecure = %v, want false", admin["insecure"])
+	}
+
+	server := out["server"].(map[string]any)
+	if server["port"] != 8080 || server["shutdown_timeout"] != "20s" {
+		t.Errorf("server = %v, want port 8080 and shutdown_timeout 20s", server)
+	}
+
+	log := out["log"].(map[string]any)
+	if rateLimit, ok := log["rate_limit"].(map[string]any); !ok || rateLimit["info"] != 0 {
+		t.Errorf("log.rate_limit = %v, want a nested map", log["rate_limit"])
+	}
+	if keys, ok := log["redact_keys"].([]string); !ok || len(keys) != 1 {
+		t.Errorf("log.redact_keys = %v, want [ssn]", log["redact_keys"])
+	}
+
+	// An unset secret is shown as empty, so it is clear that none is set
+	cfg.Admin.Token = ""
+	if token := Redacted(cfg)["admin"].(map[string]any)["token"]; token != "" {
+		t.Errorf("unset admin.token = %v, want empty", token)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	old := Default()
+	if keys := Changed(old, Default()); len(keys) != 0 {
+		t.Errorf("Changed() of equal configurations = %v, want none", keys)
+	}
+
+	cur := Default()
+	cur.Server.Port = 9000
+	cur.Log.RateLimit.Info = 100
+	cur.Log.RedactKeys = []string{"ssn"}
+	cur.Admin.Token = "s3cret"
+	cur.Metrics.DurationBuckets = []float64{0.1, 1}
+
+	want := []string{
+		"admin.token",
+		"log.rate_limit.info",
+		"log.redact_keys",
+		"metrics.duration_buckets",
+		"server.port",
+	}
+	if got := Changed(old, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changed() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce groups bursts of filesystem events, such as the several
+// events produced by a Kubernetes ConfigMap update, into a single reload.
+const watchDebounce = 250 * time.Millisecond
+
+// generation counts how many configurations have been made current.
+var generation atomic.Uint64
+
+// Generation returns the number of configurations that have been made
+// current, starting at 1 for the initial configuration.
+func Generation() uint64 {
+	return generation.Load()
+}
+
+// Changed returns the dotted config file keys whose values differ between
+// old and cur, sorted. Only keys are returned, so secrets are never exposed.
+func Changed(old, cur *Config) []string {
+	var keys []string
+	diffStruct("", reflect.ValueOf(old).Elem(), reflect.ValueOf(cur).Elem(), &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+// diffStruct appends the keys of fields that differ between a and b.
+func diffStruct(prefix string, a, b reflect.Value, keys *[]string) {
+	t := a.Type()
+	for i := range t.NumField() {
+		key := prefix + t.Field(i).Tag.Get("yaml")
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			diffStruct(key+".", a.Field(i), b.Field(i), keys)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			*keys = append(*keys, key)
+		}
+	}
+}
+
+// WatchFiles watches the given files and calls onChange whenever the content
+// of any of them changes. It blocks until ctx is cancelled.
+//
+// The parent directories are watched rather than the files themselves, so
+// atomic replacements are detected. This includes the Kubernetes ConfigMap
+// volume update, which swaps the "..data" symlink to a new directory instead
+// of writing to the file. Events that leave every file's content unchanged
+// are ignored. Empty paths are skipped; relative paths are resolved against
+// the working directory.
+func WatchFiles(ctx context.Context, onChange func(), paths ...string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	hashes := make(map[string][]byte)
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		path = absPath(path)
+		hashes[path] = hashFile(path)
+
+		dir := filepath.Dir(path)
+		if !dirs[dir] {
+			if err := watcher.Add(dir); err != nil {
+				return err
+			}
+			dirs[dir] = true
+		}
+	}
+
+	debounce := time.NewTimer(0)
+	<-debounce.C
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if relevant(event, hashes) {
+				debounce.Reset(watchDebounce)
+			}
+
+		case <-debounce.C:
+			changed := false
+			for path, old := range hashes {
+				if current := hashFile(path); !bytes.Equal(current, old) {
+					hashes[path] = current
+					changed = true
+				}
+			}
+			if changed {
+				onChange()
+			}
+		}
+	}
+}
+
+// relevant reports whether a directory event may have changed one of the
+// watched files, either directly or through a ConfigMap symlink swap.
+func relevant(event fsnotify.Event, files map[string][]byte) bool {
+	name := absPath(event.Name)
+	if filepath.Base(name) == "..data" {
+		return true
+	}
+
+	_, ok := files[name]
+	return ok
+}
+
+// hashFile returns the SHA-256 of the file's content, or nil if the file
+// cannot be read (for example while it is being replaced).
+func hashFile(path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// absPath returns path as an absolute, cleaned path, so that a file has the
+// same key however it was named. It falls back to the cleaned path if the
+// working directory cannot be determined.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
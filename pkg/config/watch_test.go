@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// watch runs WatchFiles on paths until the test ends and returns the number
+// of onChange calls so far.
+func watch(t *testing.T, paths ...string) *atomic.Int32 {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("WatchFiles() error = %v", err)
+		}
+	})
+
+	var calls atomic.Int32
+	go func() {
+		done <- WatchFiles(ctx, func() { calls.Add(1) }, paths...)
+	}()
+
+	// Give the watcher time to hash the files and watch their directories
+	time.Sleep(100 * time.Millisecond)
+	return &calls
+}
+
+// waitForCalls waits until calls reaches want, then for a few debounce
+// periods more, and fails unless calls is exactly want.
+func waitForCalls(t *testing.T, calls *atomic.Int32, want int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(3 * watchDebounce)
+
+	if got := calls.Load(); got != want {
+		t.Errorf("onChange called %d times, want %d", got, want)
+	}
+}
+
+func TestWatchFilesDebounce(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", "server:\n  port: 8080\n")
+	calls := watch(t, path)
+
+	// A burst of writes within the debounce period causes a single reload
+	for _, port := range []string{"8081", "8082", "8083"} {
+		writeFile(t, dir, "config.yaml", "server:\n  port: "+port+"\n")
+		time.Sleep(watchDebounce / 10)
+	}
+	waitForCalls(t, calls, 1)
+
+	// Rewriting the same content and changing other files are ignored
+	writeFile(t, dir, "config.yaml", "server:\n  port: 8083\n")
+	writeFile(t, dir, "other.yaml", "unrelated")
+	waitForCalls(t, calls, 1)
+
+	// A later change is picked up again
+	writeFile(t, dir, "config.yaml", "server:\n  port: 8084\n")
+	waitForCalls(t, calls, 2)
+}
+
+func TestWatchFilesConfigMapSwap(t *testing.T) {
+	// Lay out a ConfigMap volume: the file is a symlink through "..data",
+	// which points at a timestamped directory holding the content
+	dir := t.TempDir()
+	swap := func(version, content string) {
+		t.Helper()
+
+		target := filepath.Join(dir, "..2026_10_16_"+version)
+		if err := os.Mkdir(target, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, target, "config.yaml", content)
+
+		tmp := filepath.Join(dir, "..data_tmp")
+		if err := os.Symlink(filepath.Base(target), tmp); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	swap("1", "log:\n  level: info\n")
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := watch(t, "", path)
+
+	// The kubelet never writes the file itself; it swaps "..data"
+	swap("2", "log:\n  level: debug\n")
+	waitForCalls(t, calls, 1)
+
+	// A swap to identical content, as on a change to another key of the
+	// ConfigMap, does not trigger a reload
+	swap("3", "log:\n  level: debug\n")
+	waitForCalls(t, calls, 1)
+}
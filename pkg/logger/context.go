@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// WithContext returns a copy of ctx carrying l, for example a logger with
+// request-scoped fields built with With. FromContext retrieves it.
+func WithContext(ctx context.Context, l zerolog.Logger) context.Context {
+	return l.WithContext(ctx)
+}
+
+// FromContext returns the logger stored in ctx by WithContext, or the
+// global logger if there is none.
+//
+// The returned logger is set to the level in effect for the caller's
+// component when FromContext is called, so runtime overrides apply as they
+// do for Debug, Info and the other global helpers. It is also bound to ctx,
+// so its events carry the trace and span IDs of the span in ctx. Call
+// FromContext for each log statement rather than keeping the result.
+//
+// Example usage:
+//
+//	logger.FromContext(r.Context()).Info().Msg("Stress test completed")
+func FromContext(ctx context.Context) *zerolog.Logger {
+	l := contextLogger(ctx).Level(minLevel(1))
+	return &l
+}
+
+// contextLogger returns the logger stored in ctx, or the global logger if
+// there is none, bound to ctx.
+func contextLogger(ctx context.Context) zerolog.Logger {
+	base := zerolog.Ctx(ctx)
+	if base.GetLevel() == zerolog.Disabled {
+		base = &log
+	}
+	return base.With().Ctx(ctx).Logger()
+}
@@ -0,0 +1,264 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// levelState is an immutable snapshot of the level configuration. It is
+// replaced as a whole, so readers never take a lock.
+type levelState struct {
+	// configured is the level set by Init or SetLevel.
+	configured zerolog.Level
+
+	// override, when non-nil, is a runtime override set through SetOverride.
+	override *override
+}
+
+// override is a runtime level change, optionally limited in time.
+type override struct {
+	id         uint64
+	level      zerolog.Level
+	components map[string]zerolog.Level
+	expiresAt  time.Time
+}
+
+// Levels describes the effective log level configuration.
+type Levels struct {
+	// Level is the level applied to components without an override.
+	Level string `json:"level"`
+
+	// Configured is the level from configuration, which applies again once
+	// the runtime override is cleared or expires.
+	Configured string `json:"configured_level"`
+
+	// Components maps component names to their override levels.
+	Components map[string]string `json:"components"`
+
+	// ExpiresAt is when the runtime override reverts, if it has a TTL.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// state holds the current levelState.
+var state atomic.Pointer[levelState]
+
+// overrideMu serializes changes to state.
+var overrideMu sync.Mutex
+
+// overrideID identifies overrides so that an expiry timer only reverts the
+// override it was started for.
+var overrideID atomic.Uint64
+
+// components caches the component name of each calling function.
+var components sync.Map
+
+func init() {
+	state.Store(&levelState{configured: zerolog.InfoLevel})
+}
+
+// ParseLevel parses a level name: debug, info, warn (or warning) or error.
+// Unlike the lenient parsing used by Init, unknown names are an error.
+func ParseLevel(level string) (zerolog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug", "info", "warn", "warning", "error":
+		return parseLogLevel(level), nil
+	default:
+		return zerolog.NoLevel, fmt.Errorf("unknown log level %q: must be debug, info, warn or error", level)
+	}
+}
+
+// SetOverride changes the log level at runtime, on top of the configured
+// level. An empty level keeps the current base level. components maps
+// component names to levels for those components only; a component is the
+// last element of the calling package's import path, such as "handlers",
+// "middleware" or "main". A positive ttl reverts the override automatically.
+//
+// A new override replaces any previous one. It returns the resulting levels.
+func SetOverride(level string, componentLevels map[string]string, ttl time.Duration) (Levels, error) {
+	if ttl < 0 {
+		return Levels{}, errors.New("ttl must not be negative")
+	}
+
+	overrideMu.Lock()
+	defer overrideMu.Unlock()
+
+	current := state.Load()
+	o := &override{
+		id:         overrideID.Add(1),
+		level:      effectiveBase(current),
+		components: make(map[string]zerolog.Level, len(componentLevels)),
+	}
+
+	if level != "" {
+		parsed, err := ParseLevel(level)
+		if err != nil {
+			return Levels{}, err
+		}
+		o.level = parsed
+	}
+
+	for component, componentLevel := range componentLevels {
+		if component == "" {
+			return Levels{}, errors.New("component name must not be empty")
+		}
+		parsed, err := ParseLevel(componentLevel)
+		if err != nil {
+			return Levels{}, fmt.Errorf("component %s: %w", component, err)
+		}
+		o.components[component] = parsed
+	}
+
+	if ttl > 0 {
+		o.expiresAt = time.Now().Add(ttl)
+		time.AfterFunc(ttl, func() {
+			if expireOverride(o.id) {
+				Audit().
+					Uint64("override_id", o.id).
+					Msg("Log level override expired, reverted to configured level")
+			}
+		})
+	}
+
+	store(&levelState{configured: current.configured, override: o})
+	return CurrentLevels(), nil
+}
+
+// ClearOverride removes any runtime override so the configured level applies
+// again. It reports whether an override was active.
+func ClearOverride() bool {
+	overrideMu.Lock()
+	defer overrideMu.Unlock()
+
+	current := state.Load()
+	if current.override == nil {
+		return false
+	}
+
+	store(&levelState{configured: current.configured})
+	return true
+}
+
+// CurrentLevels returns the effective level configuration.
+func CurrentLevels() Levels {
+	s := state.Load()
+
+	levels := Levels{
+		Level:      effectiveBase(s).String(),
+		Configured: s.configured.String(),
+		Components: map[string]string{},
+	}
+	if s.override != nil {
+		for component, level := range s.override.components {
+			levels.Components[component] = level.String()
+		}
+		if !s.override.expiresAt.IsZero() {
+			expiresAt := s.override.expiresAt
+			levels.ExpiresAt = &expiresAt
+		}
+	}
+	return levels
+}
+
+// setConfigured replaces the configured level, keeping any override.
+func setConfigured(level zerolog.Level) {
+	overrideMu.Lock()
+	defer overrideMu.Unlock()
+
+	store(&levelState{configured: level, override: state.Load().override})
+}
+
+// expireOverride clears the override if it is still the one identified by
+// id, and reports whether it did.
+func expireOverride(id uint64) bool {
+	overrideMu.Lock()
+	defer overrideMu.Unlock()
+
+	current := state.Load()
+	if current.override == nil || current.override.id != id {
+		return false
+	}
+
+	store(&levelState{configured: current.configured})
+	return true
+}
+
+// store makes s current and lowers the zerolog global level to the most
+// verbose level in use, so that component overrides are not filtered out
+// before enabled gets to see them.
+func store(s *levelState) {
+	lowest := effectiveBase(s)
+	if s.override != nil {
+		for level := range maps.Values(s.override.components) {
+			lowest = min(lowest, level)
+		}
+	}
+
+	state.Store(s)
+	zerolog.SetGlobalLevel(lowest)
+}
+
+// effectiveBase returns the level that applies to components without an
+// override.
+func effectiveBase(s *levelState) zerolog.Level {
+	if s.override != nil {
+		return s.override.level
+	}
+	return s.configured
+}
+
+// enabled reports whether an event at level should be logged for the caller
+// skip frames above enabled.
+func enabled(level zerolog.Level, skip int) bool {
+	return level >= minLevel(skip+1)
+}
+
+// minLevel returns the lowest level logged for the caller skip frames above
+// minLevel. The caller's component is only looked up when component
+// overrides are active, keeping the common path cheap.
+func minLevel(skip int) zerolog.Level {
+	s := state.Load()
+	if s.override == nil || len(s.override.components) == 0 {
+		return effectiveBase(s)
+	}
+
+	if componentLevel, ok := s.override.components[callerComponent(skip+1)]; ok {
+		return componentLevel
+	}
+	return s.override.level
+}
+
+// callerComponent returns the component name of the function skip frames
+// above callerComponent: the last element of its package import path.
+func callerComponent(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	if component, ok := components.Load(pc); ok {
+		return component.(string)
+	}
+
+	component := componentOf(runtime.FuncForPC(pc).Name())
+	components.Store(pc, component)
+	return component
+}
+
+// componentOf extracts the package name from a fully qualified function name
+// such as "github.com/org/app/internal/handlers.(*job).run.func1".
+func componentOf(funcName string) string {
+	if i := strings.LastIndex(funcName, "/"); i >= 0 {
+		funcName = funcName[i+1:]
+	}
+	if i := strings.Index(funcName, "."); i >= 0 {
+		funcName = funcName[:i]
+	}
+	return funcName
+}
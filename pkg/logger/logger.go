@@ -1,48 +1,75 @@
 // Package logger provides a structured logging solution using zerolog.
 //
-// The package configures a global logger instance with support for configurable
-// log levels via the LOG_LEVEL environment variable. It produces JSON-formatted
-// logs suitable for production environments and log aggregation systems.
+// The package configures a global logger instance with a configurable log
+// level. By default it produces JSON-formatted logs suitable for production
+// environments and log aggregation systems. Configure selects a
+// human-readable console format instead, adds a size-rotated log file, and
+// sets up sampling of request logs and per-level rate limits so load tests
+// cannot flood the log pipeline. Query and Headers log request data with
+// sensitive values redacted.
 //
 // Supported log levels: debug, info, warn, error (default: info)
 //
+// The level can be changed at runtime with SetOverride, either globally or
+// for individual components (packages), optionally reverting after a TTL.
+//
+// Request handling code logs through the logger carried by the request
+// context, retrieved with FromContext. Middleware stores it with WithContext,
+// seeded with request-scoped fields such as the request ID. Events bound to a
+// context carry the trace_id and span_id of its OpenTelemetry span, so log
+// lines can be joined with traces.
+//
 // Example usage:
 //
-//	logger.Init()
+//	logger.Init("info")
 //	logger.Info().Msg("Application started")
 //	logger.Debug().Str("key", "value").Msg("Debug information")
+//	logger.FromContext(r.Context()).Info().Msg("Handled request")
 package logger
 
 import (
-	"os"
+	"context"
 	"strings"
-	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // log holds the global logger instance used throughout the application.
 var log zerolog.Logger
 
-// Init initializes the global logger with the configuration specified
-// by the LOG_LEVEL environment variable. If LOG_LEVEL is not set or
-// contains an invalid value, it defaults to "info" level.
+// Init initializes the global logger with the given log level. If level is
+// empty or contains an invalid value, it defaults to "info" level.
 //
-// The logger outputs structured JSON to stdout with timestamps in RFC3339 format.
-func Init() {
-	// Configure zerolog to use RFC3339 timestamps for consistency
-	zerolog.TimeFieldFormat = time.RFC3339
-
-	// Parse log level from environment variable
-	level := parseLogLevel(os.Getenv("LOG_LEVEL"))
-	zerolog.SetGlobalLevel(level)
-
-	// Create logger with timestamp and caller information
-	log = zerolog.New(os.Stdout).
-		With().
-		Timestamp().
-		Caller().
-		Logger()
+// The logger outputs structured JSON to stdout with timestamps in RFC3339
+// format, without sampling or rate limits. Use Configure for other setups.
+func Init(level string) {
+	// Without a log file, Configure cannot fail
+	_ = Configure(Options{Level: level})
+}
+
+// traceHook adds the trace and span IDs of the span in an event's context,
+// if any, to the event. The span changes within a request, for example in
+// stress workers, so the IDs are looked up per event rather than seeded into
+// the context logger.
+type traceHook struct{}
+
+// Run implements zerolog.Hook.
+func (traceHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	spanContext := trace.SpanContextFromContext(e.GetCtx())
+	if !spanContext.IsValid() {
+		return
+	}
+	e.Str("trace_id", spanContext.TraceID().String()).
+		Str("span_id", spanContext.SpanID().String())
+}
+
+// SetLevel changes the configured log level at runtime, for example after a
+// configuration reload. Invalid values fall back to "info" as in Init. A
+// runtime override set through SetOverride keeps precedence until it is
+// cleared or expires.
+func SetLevel(level string) {
+	setConfigured(parseLogLevel(level))
 }
 
 // parseLogLevel converts a string log level to a zerolog.Level.
@@ -66,24 +93,36 @@ func parseLogLevel(levelStr string) zerolog.Level {
 // Debug returns a zerolog.Event for logging at debug level.
 // Debug logs are intended for detailed troubleshooting information.
 func Debug() *zerolog.Event {
+	if !enabled(zerolog.DebugLevel, 1) {
+		return nil
+	}
 	return log.Debug()
 }
 
 // Info returns a zerolog.Event for logging at info level.
 // Info logs are intended for general operational information.
 func Info() *zerolog.Event {
+	if !enabled(zerolog.InfoLevel, 1) {
+		return nil
+	}
 	return log.Info()
 }
 
 // Warn returns a zerolog.Event for logging at warn level.
 // Warn logs indicate potentially harmful situations.
 func Warn() *zerolog.Event {
+	if !enabled(zerolog.WarnLevel, 1) {
+		return nil
+	}
 	return log.Warn()
 }
 
 // Error returns a zerolog.Event for logging at error level.
 // Error logs indicate error conditions that should be addressed.
 func Error() *zerolog.Event {
+	if !enabled(zerolog.ErrorLevel, 1) {
+		return nil
+	}
 	return log.Error()
 }
 
@@ -93,13 +132,38 @@ func Fatal() *zerolog.Event {
 	return log.Fatal()
 }
 
+// Audit returns a zerolog.Event for audit logs of administrative changes.
+// Audit events are written at warn level regardless of the configured and
+// overridden levels, so changes such as raising the log level itself are
+// always recorded.
+func Audit() *zerolog.Event {
+	return audit(log)
+}
+
+// AuditContext is like Audit but writes through the logger in ctx (see
+// FromContext), so the event carries its request-scoped fields.
+func AuditContext(ctx context.Context) *zerolog.Event {
+	return audit(contextLogger(ctx))
+}
+
+// audit starts an audit event on l. Events without a level are written
+// whatever the level of l.
+func audit(l zerolog.Logger) *zerolog.Event {
+	return l.Log().
+		Str(zerolog.LevelFieldName, zerolog.WarnLevel.String()).
+		Bool("audit", true)
+}
+
 // With creates a child logger with additional context fields.
 // This is useful for adding request-specific or operation-specific context.
+// The child logger is fixed to the base level in effect when it is created
+// and does not honor component overrides.
 func With() zerolog.Context {
-	return log.With()
+	return Logger().With()
 }
 
 // Logger returns the underlying zerolog.Logger instance for advanced usage.
+// Like With, it is fixed to the base level in effect when it is called.
 func Logger() zerolog.Logger {
-	return log
+	return log.Level(effectiveBase(state.Load()))
 }
@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Supported values for Options.Format.
+const (
+	// FormatJSON writes one JSON object per line, for log aggregation.
+	FormatJSON = "json"
+
+	// FormatConsole writes colored, human-readable lines, for local runs.
+	FormatConsole = "console"
+)
+
+// Options configures the global logger set up by Configure.
+type Options struct {
+	// Level is the minimum log level, as accepted by Init.
+	Level string
+
+	// Format is FormatJSON or FormatConsole and applies to stdout. Empty
+	// means FormatJSON.
+	Format string
+
+	// File, when set, also writes logs to this file as JSON. The file is
+	// rotated once it reaches FileMaxSizeMiB, keeping FileMaxBackups old
+	// files.
+	File           string
+	FileMaxSizeMiB int
+	FileMaxBackups int
+
+	// RequestSampleEvery writes only one in every RequestSampleEvery info
+	// and debug events of loggers returned by Sampled, such as the log line
+	// of every successful request. 0 and 1 write every event.
+	RequestSampleEvery int
+
+	// RateLimits caps the events written per second at each level. Events
+	// beyond the limit are dropped until the next second. Levels without a
+	// positive limit are not limited; fatal and audit events never are.
+	RateLimits map[zerolog.Level]int
+
+	// RedactKeys are the query parameter and header names whose values are
+	// replaced by Redacted in Query and Headers. Names match if they contain
+	// a key, ignoring case.
+	RedactKeys []string
+}
+
+// output is the log file currently written to, if any.
+var output io.Closer
+
+// sampling holds the samplers applying Options.RequestSampleEvery and
+// Options.RateLimits. A nil sampler lets every event through.
+type sampling struct {
+	request zerolog.Sampler
+	rate    zerolog.Sampler
+}
+
+// currentSampling holds the sampling set by Configure or SetSampling.
+var currentSampling atomic.Pointer[sampling]
+
+// Configure sets up the global logger from opts, replacing any previous
+// configuration. It returns an error if the log file cannot be opened, in
+// which case the previous configuration stays in effect.
+//
+// Configure is meant to be called at startup, before logging from other
+// goroutines begins. Afterwards, the level, sampling, rate limits and
+// redacted keys can be changed safely with SetLevel, SetSampling and
+// SetRedactKeys.
+func Configure(opts Options) error {
+	// Configure zerolog to use RFC3339 timestamps for consistency
+	zerolog.TimeFieldFormat = time.RFC3339
+
+	var stdout io.Writer = os.Stdout
+	if opts.Format == FormatConsole {
+		stdout = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	}
+
+	writer := stdout
+	var file *lumberjack.Logger
+	if opts.File != "" {
+		// Open the file once up front so a bad path fails at startup
+		// rather than on every write
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0o755); err != nil {
+			return fmt.Errorf("logger: create log directory: %w", err)
+		}
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("logger: open log file: %w", err)
+		}
+		_ = f.Close()
+
+		file = &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    max(opts.FileMaxSizeMiB, 1),
+			MaxBackups: opts.FileMaxBackups,
+		}
+		writer = zerolog.MultiLevelWriter(stdout, file)
+	}
+
+	SetSampling(opts.RequestSampleEvery, opts.RateLimits)
+	SetRedactKeys(opts.RedactKeys)
+
+	setConfigured(parseLogLevel(opts.Level))
+
+	// Create logger with timestamp and caller information. The rate limits
+	// are looked up per event, so SetSampling also applies to loggers
+	// derived before it is called
+	log = zerolog.New(writer).
+		With().
+		Timestamp().
+		Caller().
+		Logger().
+		Hook(traceHook{}).
+		Sample(liveRateLimiter{})
+
+	if output != nil {
+		_ = output.Close()
+	}
+	output = nil
+	if file != nil {
+		output = file
+	}
+	return nil
+}
+
+// SetSampling replaces the request log sampling and the per-level rate
+// limits at runtime, for example after a configuration reload. The values
+// mean the same as Options.RequestSampleEvery and Options.RateLimits. The
+// counts of the previous samplers are discarded. It is safe for concurrent
+// use.
+func SetSampling(requestSampleEvery int, rateLimits map[zerolog.Level]int) {
+	s := &sampling{rate: newRateLimiter(rateLimits)}
+	if requestSampleEvery > 1 {
+		every := &zerolog.BasicSampler{N: uint32(requestSampleEvery)}
+		s.request = &zerolog.LevelSampler{DebugSampler: every, InfoSampler: every}
+	}
+	currentSampling.Store(s)
+}
+
+// liveRateLimiter applies the current Options.RateLimits to every event.
+type liveRateLimiter struct{}
+
+// Sample implements zerolog.Sampler.
+func (liveRateLimiter) Sample(level zerolog.Level) bool {
+	s := currentSampling.Load()
+	return s == nil || s.rate == nil || s.rate.Sample(level)
+}
+
+// newRateLimiter returns a sampler letting through at most limits[level]
+// events per second at each level, or nil if no level is limited.
+func newRateLimiter(limits map[zerolog.Level]int) zerolog.Sampler {
+	limiter := func(level zerolog.Level) zerolog.Sampler {
+		if limits[level] <= 0 {
+			return nil
+		}
+		return &zerolog.BurstSampler{Burst: uint32(limits[level]), Period: time.Second}
+	}
+
+	sampler := &zerolog.LevelSampler{
+		DebugSampler: limiter(zerolog.DebugLevel),
+		InfoSampler:  limiter(zerolog.InfoLevel),
+		WarnSampler:  limiter(zerolog.WarnLevel),
+		ErrorSampler: limiter(zerolog.ErrorLevel),
+	}
+	if sampler.DebugSampler == nil && sampler.InfoSampler == nil &&
+		sampler.WarnSampler == nil && sampler.ErrorSampler == nil {
+		return nil
+	}
+	return sampler
+}
+
+// Sampled returns l with request log sampling applied (see
+// Options.RequestSampleEvery), for high-volume info logs such as one line
+// per request. Warnings and errors are never sampled. Rate limits still
+// apply to the events that are sampled in.
+func Sampled(l *zerolog.Logger) *zerolog.Logger {
+	s := currentSampling.Load()
+	if s == nil || s.request == nil {
+		return l
+	}
+
+	sampled := l.Sample(chainSampler{s.request, liveRateLimiter{}})
+	return &sampled
+}
+
+// chainSampler lets an event through only if every sampler in it does,
+// asking them in order. Nil samplers let every event through.
+type chainSampler []zerolog.Sampler
+
+// Sample implements zerolog.Sampler.
+func (c chainSampler) Sample(level zerolog.Level) bool {
+	for _, sampler := range c {
+		if sampler != nil && !sampler.Sample(level) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// countLines returns the number of log lines in buf.
+func countLines(buf *bytes.Buffer) int {
+	return strings.Count(buf.String(), "\n")
+}
+
+func TestSampledWritesOneInEvery(t *testing.T) {
+	if err := Configure(Options{Level: "debug", RequestSampleEvery: 4}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Init("info") })
+
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+	sampled := Sampled(&l)
+
+	for range 20 {
+		sampled.Info().Msg("request")
+	}
+	if got := countLines(&buf); got != 5 {
+		t.Errorf("wrote %d of 20 sampled info events, want 5", got)
+	}
+
+	// Warnings and errors are never sampled
+	buf.Reset()
+	for range 10 {
+		sampled.Warn().Msg("slow request")
+		sampled.Error().Msg("failed request")
+	}
+	if got := countLines(&buf); got != 20 {
+		t.Errorf("wrote %d of 20 warnings and errors, want all", got)
+	}
+}
+
+func TestSampledWithoutSampling(t *testing.T) {
+	if err := Configure(Options{Level: "info", RequestSampleEvery: 1}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Init("info") })
+
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+	if Sampled(&l) != &l {
+		t.Error("Sampled() wrapped the logger although sampling is disabled")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(nil) != nil || newRateLimiter(map[zerolog.Level]int{zerolog.InfoLevel: 0}) != nil {
+		t.Error("newRateLimiter() without positive limits is not nil")
+	}
+
+	var buf bytes.Buffer
+	l := zerolog.New(&buf).Sample(newRateLimiter(map[zerolog.Level]int{zerolog.InfoLevel: 3}))
+
+	for range 10 {
+		l.Info().Msg("flood")
+	}
+	if got := countLines(&buf); got != 3 {
+		t.Errorf("wrote %d of 10 info events within a second, want 3", got)
+	}
+
+	buf.Reset()
+	for range 10 {
+		l.Warn().Msg("unlimited")
+	}
+	if got := countLines(&buf); got != 10 {
+		t.Errorf("wrote %d of 10 warnings without a warn limit, want all", got)
+	}
+}
+
+func TestSetSamplingAppliesToExistingLoggers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Configure(Options{Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Init("info") })
+
+	// A request logger derived before the change, as held by a running
+	// request, writes to buf
+	l := Logger().Output(&buf)
+	for range 10 {
+		l.Info().Msg("before")
+	}
+	if got := countLines(&buf); got != 10 {
+		t.Fatalf("wrote %d of 10 events without limits, want all", got)
+	}
+
+	buf.Reset()
+	SetSampling(1, map[zerolog.Level]int{zerolog.InfoLevel: 2})
+	for range 10 {
+		l.Info().Msg("limited")
+	}
+	if got := countLines(&buf); got != 2 {
+		t.Errorf("wrote %d of 10 events after SetSampling, want the new limit of 2", got)
+	}
+
+	buf.Reset()
+	SetSampling(5, nil)
+	for range 10 {
+		Sampled(&l).Info().Msg("sampled")
+	}
+	if got := countLines(&buf); got != 2 {
+		t.Errorf("wrote %d of 10 sampled events, want 2", got)
+	}
+}
+
+// logObject logs obj and returns the decoded object.
+func logObject(t *testing.T, obj zerolog.LogObjectMarshaler) map[string]any {
+	t.Helper()
+
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+	l.Info().Object("obj", obj).Send()
+
+	var line struct {
+		Obj map[string]any `json:"obj"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	return line.Obj
+}
+
+func TestRedaction(t *testing.T) {
+	t.Cleanup(func() { SetRedactKeys(nil) })
+
+	query := logObject(t, Query(url.Values{
+		"user":         {"alice"},
+		"access_token": {"abc"},
+		"tag":          {"a", "b"},
+	}))
+	if query["access_token"] != Redacted {
+		t.Errorf("access_token = %v, want %s", query["access_token"], Redacted)
+	}
+	if query["user"] != "alice" {
+		t.Errorf("user = %v, want alice", query["user"])
+	}
+	if tags, ok := query["tag"].([]any); !ok || len(tags) != 2 {
+		t.Errorf("tag = %v, want both values", query["tag"])
+	}
+
+	headers := logObject(t, Headers(http.Header{
+		"Authorization": {"Bearer secret"},
+		"Cookie":        {"session=1"},
+		"X-Api-Key":     {"k"},
+		"Accept":        {"application/json"},
+	}))
+	for _, name := range []string{"Authorization", "Cookie", "X-Api-Key"} {
+		if headers[name] != Redacted {
+			t.Errorf("header %s = %v, want %s", name, headers[name], Redacted)
+		}
+	}
+	if headers["Accept"] != "application/json" {
+		t.Errorf("Accept = %v, want it unredacted", headers["Accept"])
+	}
+
+	// Custom keys replace the defaults and match ignoring case
+	SetRedactKeys([]string{" SSN "})
+	custom := logObject(t, Query(url.Values{"user_ssn": {"123"}, "token": {"abc"}}))
+	if custom["user_ssn"] != Redacted || custom["token"] != "abc" {
+		t.Errorf("with custom keys: %v, want only user_ssn redacted", custom)
+	}
+}
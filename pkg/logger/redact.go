@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// Redacted replaces the values of sensitive query parameters and headers.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are the redacted names used when Options.RedactKeys is
+// empty.
+var DefaultRedactKeys = []string{
+	"authorization",
+	"cookie",
+	"token",
+	"password",
+	"secret",
+	"api_key",
+	"api-key",
+	"apikey",
+	"signature",
+	"credential",
+}
+
+// redactKeys holds the lowercased names set by Configure or SetRedactKeys.
+var redactKeys atomic.Pointer[[]string]
+
+func init() {
+	SetRedactKeys(nil)
+}
+
+// SetRedactKeys replaces the redacted query parameter and header names at
+// runtime, for example after a configuration reload. Like
+// Options.RedactKeys, an empty list selects DefaultRedactKeys. It is safe for
+// concurrent use.
+func SetRedactKeys(keys []string) {
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+
+	lowered := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			lowered = append(lowered, key)
+		}
+	}
+	redactKeys.Store(&lowered)
+}
+
+// sensitive reports whether the values of name must be redacted.
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, key := range *redactKeys.Load() {
+		if strings.Contains(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the query parameters in values as a log object, with the
+// values of sensitive parameters replaced by Redacted:
+//
+//	logger.Info().Object("query", logger.Query(r.URL.Query())).Msg("...")
+func Query(values url.Values) zerolog.LogObjectMarshaler {
+	return redactedValues(values)
+}
+
+// Headers returns h as a log object, with the values of sensitive headers
+// such as Authorization and Cookie replaced by Redacted.
+func Headers(h http.Header) zerolog.LogObjectMarshaler {
+	return redactedValues(h)
+}
+
+// redactedValues logs multi-valued fields, redacting sensitive ones.
+type redactedValues map[string][]string
+
+// MarshalZerologObject implements zerolog.LogObjectMarshaler. Fields with a
+// single value are logged as a string, others as an array.
+func (v redactedValues) MarshalZerologObject(e *zerolog.Event) {
+	for name, values := range v {
+		switch {
+		case sensitive(name):
+			e.Str(name, Redacted)
+		case len(values) == 1:
+			e.Str(name, values[0])
+		default:
+			e.Strs(name, values)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// durationHistogram records request durations in one histogram vector per
+// route class, all exposed under the same metric name. Prometheus allows
+// series of one histogram to use different buckets, as long as each series
+// consistently uses the same ones, which holds because a route always maps
+// to the same class.
+type durationHistogram struct {
+	exemplars bool
+	classes   map[string]*prometheus.HistogramVec
+	fallback  *prometheus.HistogramVec
+}
+
+// newDurationHistogram builds the request duration histogram from opts.
+func newDurationHistogram(opts Options) *durationHistogram {
+	newVec := func(buckets []float64) *prometheus.HistogramVec {
+		histogramOpts := prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests in seconds",
+			Buckets: buckets,
+		}
+		if opts.NativeHistograms {
+			histogramOpts.NativeHistogramBucketFactor = opts.NativeHistogramBucketFactor
+			histogramOpts.NativeHistogramMaxBucketNumber = 160
+			histogramOpts.NativeHistogramMinResetDuration = time.Hour
+		}
+		return prometheus.NewHistogramVec(histogramOpts, []string{"path", "method", "code"})
+	}
+
+	h := &durationHistogram{
+		exemplars: opts.Exemplars,
+		classes:   make(map[string]*prometheus.HistogramVec),
+		fallback:  newVec(opts.DurationBuckets),
+	}
+	for _, class := range opts.RouteClasses {
+		vec := newVec(class.Buckets)
+		for _, route := range class.Routes {
+			if _, ok := h.classes[route]; !ok {
+				h.classes[route] = vec
+			}
+		}
+	}
+	return h
+}
+
+// vecs returns every distinct histogram vector, the fallback first.
+func (h *durationHistogram) vecs() []*prometheus.HistogramVec {
+	vecs := []*prometheus.HistogramVec{h.fallback}
+	for _, vec := range h.classes {
+		if !slices.Contains(vecs, vec) {
+			vecs = append(vecs, vec)
+		}
+	}
+	return vecs
+}
+
+// observe records a request duration, with exemplar labels if exemplars are
+// enabled and any are given.
+func (h *durationHistogram) observe(path, method, code string, seconds float64, exemplar map[string]string) {
+	vec, ok := h.classes[path]
+	if !ok {
+		vec = h.fallback
+	}
+
+	observer := vec.WithLabelValues(path, method, code)
+	if h.exemplars && len(exemplar) > 0 {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(seconds, exemplar)
+		return
+	}
+	observer.Observe(seconds)
+}
+
+// Describe implements prometheus.Collector.
+func (h *durationHistogram) Describe(ch chan<- *prometheus.Desc) {
+	for _, vec := range h.vecs() {
+		vec.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (h *durationHistogram) Collect(ch chan<- prometheus.Metric) {
+	for _, vec := range h.vecs() {
+		vec.Collect(ch)
+	}
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// durationHistograms returns the http_request_duration_seconds histograms
+// gathered from g, keyed by path label.
+func durationHistograms(t *testing.T, g prometheus.Gatherer) map[string]*dto.Histogram {
+	t.Helper()
+
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	histograms := make(map[string]*dto.Histogram)
+	for _, family := range families {
+		if family.GetName() != "http_request_duration_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "path" {
+					histograms[label.GetValue()] = m.GetHistogram()
+				}
+			}
+		}
+	}
+	return histograms
+}
+
+// upperBounds returns the upper bounds of the classic buckets of h.
+func upperBounds(h *dto.Histogram) []float64 {
+	bounds := make([]float64, 0, len(h.GetBucket()))
+	for _, bucket := range h.GetBucket() {
+		bounds = append(bounds, bucket.GetUpperBound())
+	}
+	return bounds
+}
+
+func TestDurationBucketsPerRouteClass(t *testing.T) {
+	promRegistry := prometheus.NewRegistry()
+	opts := DefaultOptions()
+	opts.Registerer = promRegistry
+	opts.GoCollector = false
+	opts.ProcessCollector = false
+	opts.DurationBuckets = []float64{0.1, 1}
+	opts.RouteClasses = []RouteClass{
+		{Name: "slow", Routes: []string{"/slow", "/slower"}, Buckets: []float64{10, 60}},
+		// A route listed twice keeps its first class
+		{Name: "other", Routes: []string{"/slow"}, Buckets: []float64{5}},
+	}
+
+	reg, err := NewRegistry(opts)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	reg.ObserveRequestDuration("/fast", "GET", 200, 0.05, nil)
+	reg.ObserveRequestDuration("/slow", "GET", 200, 30, nil)
+	reg.ObserveRequestDuration("/slower", "GET", 200, 45, nil)
+
+	histograms := durationHistograms(t, promRegistry)
+	want := map[string][]float64{
+		"/fast":   {0.1, 1},
+		"/slow":   {10, 60},
+		"/slower": {10, 60},
+	}
+	for path, bounds := range want {
+		h, ok := histograms[path]
+		if !ok {
+			t.Errorf("no histogram for %s", path)
+			continue
+		}
+		if got := upperBounds(h); !slices.Equal(got, bounds) {
+			t.Errorf("%s buckets = %v, want %v", path, got, bounds)
+		}
+		if h.GetSampleCount() != 1 {
+			t.Errorf("%s sample count = %d, want 1", path, h.GetSampleCount())
+		}
+	}
+}
+
+func TestDefaultRouteClasses(t *testing.T) {
+	promRegistry := prometheus.NewRegistry()
+	opts := DefaultOptions()
+	opts.Registerer = promRegistry
+	opts.GoCollector = false
+	opts.ProcessCollector = false
+
+	reg, err := NewRegistry(opts)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	for _, path := range []string{"/", "/stress", "/stress/memory", "/fault"} {
+		reg.ObserveRequestDuration(path, "GET", 200, 1, nil)
+	}
+
+	histograms := durationHistograms(t, promRegistry)
+	for _, path := range []string{"/stress", "/stress/memory", "/fault"} {
+		if got := upperBounds(histograms[path]); !slices.Equal(got, StressDurationBuckets) {
+			t.Errorf("%s buckets = %v, want StressDurationBuckets", path, got)
+		}
+	}
+	if got := upperBounds(histograms["/"]); !slices.Equal(got, prometheus.DefBuckets) {
+		t.Errorf("/ buckets = %v, want prometheus.DefBuckets", got)
+	}
+}
+
+func TestDurationNativeHistograms(t *testing.T) {
+	for _, native := range []bool{false, true} {
+		promRegistry := prometheus.NewRegistry()
+		opts := DefaultOptions()
+		opts.Registerer = promRegistry
+		opts.GoCollector = false
+		opts.ProcessCollector = false
+		opts.NativeHistograms = native
+
+		reg, err := NewRegistry(opts)
+		if err != nil {
+			t.Fatalf("NewRegistry() error = %v", err)
+		}
+		reg.ObserveRequestDuration("/", "GET", 200, 0.2, nil)
+		reg.ObserveRequestDuration("/stress", "GET", 200, 12, nil)
+
+		for path, h := range durationHistograms(t, promRegistry) {
+			// Native histograms report a schema and spans next to the
+			// classic buckets, which stay available
+			hasNative := h.Schema != nil && len(h.GetPositiveSpan()) > 0
+			if hasNative != native {
+				t.Errorf("native=%v: %s has native buckets = %v", native, path, hasNative)
+			}
+			if len(h.GetBucket()) == 0 {
+				t.Errorf("native=%v: %s has no classic buckets", native, path)
+			}
+		}
+	}
+}
@@ -1,69 +1,489 @@
 // Package metrics provides Prometheus instrumentation for the application.
 //
-// This package defines and registers Prometheus metrics for monitoring
-// HTTP requests and other application-specific telemetry. It provides
-// a clean interface for recording metrics throughout the application.
+// This package defines Prometheus metrics for monitoring HTTP requests and
+// other application-specific telemetry. Metrics are recorded through the
+// Recorder interface, implemented by Registry, which registers its collectors
+// with the prometheus.Registerer given in its Options, and by Nop, which
+// discards everything and is meant for tests. Since a Registry owns its
+// collectors, several can coexist in one process.
 //
 // Example usage:
 //
-//	metrics.Register()
-//	metrics.TrackRequest("/api/users", "GET")
+//	reg, err := metrics.NewRegistry(metrics.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	reg.TrackRequest("/api/users/{id}", "GET", 200)
+//	http.Handle("/metrics", reg.Handler())
+//
+// HTTP metrics are labeled with the route template rather than the raw URL
+// path, so the number of series stays bounded no matter which URLs clients
+// request. Requests that match no route share the UnmatchedRoute label.
 package metrics
 
 import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
-)
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-// httpRequestsTotal tracks the total number of HTTP requests processed,
-// labeled by path and method. This counter is essential for monitoring
-// request volume and traffic patterns.
-var httpRequestsTotal = prometheus.NewCounterVec(
-	prometheus.CounterOpts{
-		Name: "http_requests_total",
-		Help: "Total number of HTTP requests processed",
-	},
-	[]string{"path", "method"},
+	"github.com/moabdelazem/go-gitops-app/pkg/version"
 )
 
-// httpRequestDuration tracks the duration of HTTP requests in seconds,
-// labeled by path and method. This histogram helps identify slow endpoints
-// and monitor latency distribution.
-var httpRequestDuration = prometheus.NewHistogramVec(
-	prometheus.HistogramOpts{
-		Name:    "http_request_duration_seconds",
-		Help:    "Duration of HTTP requests in seconds",
-		Buckets: prometheus.DefBuckets,
-	},
-	[]string{"path", "method"},
-)
+// UnmatchedRoute is the path label of requests that match no route, such as
+// 404s from scanners probing random URLs.
+const UnmatchedRoute = "unmatched"
 
-// Register registers all application metrics with the default Prometheus registry.
-// This function should be called once during application startup, typically
-// in the main function before starting the HTTP server.
-//
-// Panics if metrics are already registered (duplicate registration).
-func Register() {
-	prometheus.MustRegister(httpRequestsTotal)
-	prometheus.MustRegister(httpRequestDuration)
+// sizeBuckets covers payloads from 100 bytes to 100 MB.
+var sizeBuckets = prometheus.ExponentialBuckets(100, 10, 7)
+
+// Recorder records application metrics. It is implemented by Registry and,
+// for tests, by Nop.
+type Recorder interface {
+	// TrackRequest increments the request counter for the specified route,
+	// method and status code. It should be called once for each completed
+	// HTTP request.
+	//
+	// Parameters:
+	//   - path: The route template (e.g., "/api/users/{id}"), or UnmatchedRoute.
+	//   - method: The HTTP method (e.g., "GET", "POST").
+	//   - code: The response status code.
+	TrackRequest(path, method string, code int)
+
+	// ObserveRequestDuration records the duration of an HTTP request.
+	// It should be called after the request has been processed.
+	//
+	// Parameters:
+	//   - path: The route template, or UnmatchedRoute.
+	//   - method: The HTTP method.
+	//   - code: The response status code.
+	//   - durationSeconds: The request processing time in seconds.
+	//   - exemplar: Labels identifying the request, such as {"trace_id": "..."},
+	//     attached as an exemplar when enabled. May be nil.
+	ObserveRequestDuration(path, method string, code int, durationSeconds float64, exemplar map[string]string)
+
+	// ObserveRequestSizes records the request and response body sizes of an
+	// HTTP request. It should be called after the request has been processed.
+	//
+	// Parameters:
+	//   - path: The route template, or UnmatchedRoute.
+	//   - method: The HTTP method.
+	//   - code: The response status code.
+	//   - requestBytes: The size of the request body in bytes.
+	//   - responseBytes: The size of the response body in bytes.
+	ObserveRequestSizes(path, method string, code int, requestBytes, responseBytes int64)
+
+	// StartRequestInFlight increments the in-flight request gauge. The
+	// returned function decrements it and must be called when the request
+	// completes.
+	StartRequestInFlight() func()
+
+	// StartScalingRequest records the start of an application request for
+	// the scaling signals. The returned function must be called when the
+	// request completes.
+	StartScalingRequest() func()
+
+	// AddStressQueueDepth adjusts the stress queue depth scaling signal by
+	// delta. Use +1 when a stress run starts and -1 when it finishes.
+	AddStressQueueDepth(delta int64)
+
+	// ScalingSignals returns the current scaling signal values.
+	ScalingSignals() ScalingSignals
+
+	// AddStressWorkers adjusts the running CPU stress worker count of route
+	// by delta and updates the route's saturation ratio. Use a positive
+	// delta when workers start and a negative one when they stop.
+	AddStressWorkers(route string, delta int)
+
+	// TrackStressRun increments the stress run counter for the given mode
+	// and outcome. It should be called once for each finished stress run.
+	//
+	// Parameters:
+	//   - mode: The kind of load generated (e.g., "cpu", "memory").
+	//   - outcome: How the run ended (e.g., "completed", "cancelled").
+	TrackStressRun(mode, outcome string)
+
+	// AddStressMemory adjusts the stress memory gauge by delta bytes.
+	// Use a positive delta when memory is allocated and a negative one when
+	// it is released.
+	AddStressMemory(delta float64)
+
+	// TrackInjectedFault increments the injected fault counter. It should
+	// be called once for each fault that is injected.
+	//
+	// Parameters:
+	//   - faultType: The kind of fault (e.g., "latency", "error").
+	//   - code: The injected HTTP status code, or "" if not applicable.
+	TrackInjectedFault(faultType, code string)
+
+	// TrackPanic increments the panic counter for the route template (or
+	// UnmatchedRoute) whose handler panicked. It should be called once for
+	// each recovered panic.
+	TrackPanic(route string)
+
+	// SetConfigGeneration records the generation of the active
+	// configuration. It should be called at startup and after every
+	// successful reload.
+	SetConfigGeneration(generation uint64)
+
+	// TrackConfigReload records the result of a configuration reload attempt.
+	TrackConfigReload(success bool)
 }
 
-// TrackRequest increments the request counter for the specified path and method.
-// This function should be called for each incoming HTTP request.
-//
-// Parameters:
-//   - path: The request URL path (e.g., "/api/users").
-//   - method: The HTTP method (e.g., "GET", "POST").
-func TrackRequest(path, method string) {
-	httpRequestsTotal.WithLabelValues(path, method).Inc()
+// Registry owns the application's Prometheus collectors and implements
+// Recorder. Create one with NewRegistry.
+type Registry struct {
+	registerer prometheus.Registerer
+	gatherer   prometheus.Gatherer
+	exemplars  bool
+
+	// httpRequestsTotal tracks the total number of HTTP requests processed,
+	// labeled by route template, method and status code. This counter is
+	// essential for monitoring request volume, traffic patterns and error
+	// rates.
+	httpRequestsTotal *prometheus.CounterVec
+
+	// httpRequestDuration tracks the duration of HTTP requests in seconds,
+	// labeled by route template, method and status code. This histogram
+	// helps identify slow endpoints and monitor latency distribution. Its
+	// buckets and native histogram and exemplar support are set from
+	// Options.
+	httpRequestDuration *durationHistogram
+
+	// httpRequestsInFlight tracks the number of HTTP requests currently
+	// being served. Comparing it with the replica count shows the
+	// concurrency each pod was handling when the HPA made a scaling decision.
+	httpRequestsInFlight prometheus.Gauge
+
+	// httpRequestSize tracks the size of HTTP request bodies in bytes,
+	// labeled by route template, method and status code.
+	httpRequestSize *prometheus.HistogramVec
+
+	// httpResponseSize tracks the size of HTTP response bodies in bytes,
+	// labeled by route template, method and status code.
+	httpResponseSize *prometheus.HistogramVec
+
+	// stressActiveWorkers tracks the CPU stress workers currently running,
+	// labeled by the route that started them ("/stress" or "/stress/jobs").
+	stressActiveWorkers *prometheus.GaugeVec
+
+	// stressSaturation tracks the CPU stress workers currently running
+	// relative to the number of CPUs available to the process, labeled by
+	// route. A value of 1 means one busy worker per CPU; above 1 workers
+	// compete for CPU time.
+	stressSaturation *prometheus.GaugeVec
+
+	// stressWorkers holds the running worker count per route backing
+	// stressActiveWorkers and stressSaturation, so both are updated together.
+	stressWorkersMu sync.Mutex
+	stressWorkers   map[string]int
+
+	// stressRunsTotal tracks the number of finished stress runs, labeled by
+	// mode ("cpu" or "memory") and outcome ("completed" or "cancelled"). A
+	// rising cancelled count indicates clients timing out or pods being
+	// terminated mid-run.
+	stressRunsTotal *prometheus.CounterVec
+
+	// stressMemoryBytes tracks the memory currently held by memory stress
+	// runs. Comparing it with container_memory_working_set_bytes shows how
+	// much of a pod's memory usage is synthetic.
+	stressMemoryBytes prometheus.Gauge
+
+	// injectedFaultsTotal tracks synthetic faults injected for SLO and
+	// alerting demos, labeled by fault type ("latency", "error") and status
+	// code (empty for latency). Subtracting it from http_requests_total
+	// separates real failures from synthetic ones.
+	injectedFaultsTotal *prometheus.CounterVec
+
+	// panicsTotal tracks handler panics recovered by the Recovery
+	// middleware, labeled by route template. Panics injected by chaos rules
+	// are counted too.
+	panicsTotal *prometheus.CounterVec
+
+	// configGeneration tracks the generation of the active configuration.
+	// It increases by one with every successful reload, so a change in the
+	// value confirms that an updated ConfigMap has been picked up.
+	configGeneration prometheus.Gauge
+
+	// configLastReloadSuccess reports whether the most recent configuration
+	// reload succeeded (1) or failed (0). Alert on it being 0: a failed
+	// reload leaves the previous configuration active.
+	configLastReloadSuccess prometheus.Gauge
+
+	// configLastReloadTimestamp records when the configuration was last
+	// reloaded, successfully or not, as a Unix timestamp.
+	configLastReloadTimestamp prometheus.Gauge
+
+	// buildInfo exposes the build metadata of the running binary as labels
+	// on a constant 1, following the Prometheus *_build_info convention.
+	// Joining on it shows which commit each pod is running, for example:
+	//
+	//	sum by (version, commit) (build_info)
+	buildInfo *prometheus.GaugeVec
+
+	// scaling holds the current scaling signal values.
+	scaling *scalingState
 }
 
-// ObserveRequestDuration records the duration of an HTTP request.
-// This function should be called after the request has been processed.
-//
-// Parameters:
-//   - path: The request URL path.
-//   - method: The HTTP method.
-//   - durationSeconds: The request processing time in seconds.
-func ObserveRequestDuration(path, method string, durationSeconds float64) {
-	httpRequestDuration.WithLabelValues(path, method).Observe(durationSeconds)
+// NewRegistry creates a Registry configured from opts and registers its
+// collectors with opts.Registerer, or with a new prometheus.Registry if
+// opts.Registerer is nil. It returns an error if any collector cannot be
+// registered, for example because it is already registered.
+func NewRegistry(opts Options) (*Registry, error) {
+	registerer, gatherer := opts.Registerer, opts.Gatherer
+	if registerer == nil {
+		registry := prometheus.NewRegistry()
+		registerer, gatherer = registry, registry
+	}
+	if gatherer == nil {
+		// A *prometheus.Registry is both, so serve what was registered
+		if g, ok := registerer.(prometheus.Gatherer); ok {
+			gatherer = g
+		} else {
+			gatherer = prometheus.DefaultGatherer
+		}
+	}
+
+	r := &Registry{
+		registerer: registerer,
+		gatherer:   gatherer,
+		exemplars:  opts.Exemplars,
+		httpRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total number of HTTP requests processed",
+			},
+			[]string{"path", "method", "code"},
+		),
+		httpRequestDuration: newDurationHistogram(opts),
+		httpRequestsInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_requests_in_flight",
+				Help: "Number of HTTP requests currently being served",
+			},
+		),
+		httpRequestSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_size_bytes",
+				Help:    "Size of HTTP request bodies in bytes",
+				Buckets: sizeBuckets,
+			},
+			[]string{"path", "method", "code"},
+		),
+		httpResponseSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_response_size_bytes",
+				Help:    "Size of HTTP response bodies in bytes",
+				Buckets: sizeBuckets,
+			},
+			[]string{"path", "method", "code"},
+		),
+		stressActiveWorkers: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "stress_active_workers",
+				Help: "CPU stress workers currently running by route",
+			},
+			[]string{"route"},
+		),
+		stressSaturation: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "stress_saturation_ratio",
+				Help: "CPU stress workers currently running divided by the number of CPUs, by route",
+			},
+			[]string{"route"},
+		),
+		stressWorkers: make(map[string]int),
+		stressRunsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "stress_runs_total",
+				Help: "Total number of stress runs by mode and outcome",
+			},
+			[]string{"mode", "outcome"},
+		),
+		stressMemoryBytes: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "stress_memory_allocated_bytes",
+				Help: "Memory currently allocated by memory stress runs in bytes",
+			},
+		),
+		injectedFaultsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "injected_faults_total",
+				Help: "Total number of synthetic faults injected by type and status code",
+			},
+			[]string{"type", "code"},
+		),
+		panicsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "panics_total",
+				Help: "Total number of recovered handler panics by route",
+			},
+			[]string{"route"},
+		),
+		configGeneration: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "config_generation",
+				Help: "Generation of the active configuration, incremented on every successful reload",
+			},
+		),
+		configLastReloadSuccess: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "config_last_reload_success",
+				Help: "Whether the last configuration reload succeeded (1) or failed (0)",
+			},
+		),
+		configLastReloadTimestamp: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "config_last_reload_timestamp_seconds",
+				Help: "Unix timestamp of the last configuration reload attempt",
+			},
+		),
+		buildInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "build_info",
+				Help: "Build metadata of the running binary, always 1",
+			},
+			[]string{"version", "commit", "build_date", "go_version", "dirty"},
+		),
+		scaling: newScalingState(),
+	}
+
+	toRegister := []prometheus.Collector{
+		r.httpRequestsTotal,
+		r.httpRequestDuration,
+		r.httpRequestsInFlight,
+		r.httpRequestSize,
+		r.httpResponseSize,
+		r.stressActiveWorkers,
+		r.stressSaturation,
+		r.stressRunsTotal,
+		r.stressMemoryBytes,
+		r.injectedFaultsTotal,
+		r.panicsTotal,
+		r.configGeneration,
+		r.configLastReloadSuccess,
+		r.configLastReloadTimestamp,
+		r.buildInfo,
+	}
+	toRegister = append(toRegister, r.scaling.collectors()...)
+	if opts.GoCollector {
+		toRegister = append(toRegister, collectors.NewGoCollector())
+	}
+	if opts.ProcessCollector {
+		toRegister = append(toRegister, collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
+	for _, collector := range toRegister {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	info := version.Get()
+	r.buildInfo.WithLabelValues(info.Version, info.Commit, info.BuildDate, info.GoVersion, strconv.FormatBool(info.Dirty)).Set(1)
+
+	return r, nil
+}
+
+// Handler returns the HTTP handler serving the metrics of the registry's
+// gatherer. It negotiates the OpenMetrics format when exemplars are enabled,
+// and the protobuf format used by native histograms when the scraper asks
+// for it.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		r.registerer,
+		promhttp.HandlerFor(r.gatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics: r.exemplars,
+			Registry:          r.registerer,
+		}),
+	)
+}
+
+// TrackRequest implements Recorder.
+func (r *Registry) TrackRequest(path, method string, code int) {
+	r.httpRequestsTotal.WithLabelValues(path, method, strconv.Itoa(code)).Inc()
+}
+
+// ObserveRequestDuration implements Recorder.
+func (r *Registry) ObserveRequestDuration(path, method string, code int, durationSeconds float64, exemplar map[string]string) {
+	r.httpRequestDuration.observe(path, method, strconv.Itoa(code), durationSeconds, exemplar)
+}
+
+// ObserveRequestSizes implements Recorder.
+func (r *Registry) ObserveRequestSizes(path, method string, code int, requestBytes, responseBytes int64) {
+	codeLabel := strconv.Itoa(code)
+	r.httpRequestSize.WithLabelValues(path, method, codeLabel).Observe(float64(requestBytes))
+	r.httpResponseSize.WithLabelValues(path, method, codeLabel).Observe(float64(responseBytes))
+}
+
+// StartRequestInFlight implements Recorder.
+func (r *Registry) StartRequestInFlight() func() {
+	r.httpRequestsInFlight.Inc()
+	return r.httpRequestsInFlight.Dec
+}
+
+// StartScalingRequest implements Recorder.
+func (r *Registry) StartScalingRequest() func() {
+	return r.scaling.startRequest()
+}
+
+// AddStressQueueDepth implements Recorder.
+func (r *Registry) AddStressQueueDepth(delta int64) {
+	r.scaling.stressQueue.Add(delta)
+}
+
+// ScalingSignals implements Recorder.
+func (r *Registry) ScalingSignals() ScalingSignals {
+	return r.scaling.current()
+}
+
+// AddStressWorkers implements Recorder.
+func (r *Registry) AddStressWorkers(route string, delta int) {
+	r.stressWorkersMu.Lock()
+	defer r.stressWorkersMu.Unlock()
+
+	active := r.stressWorkers[route] + delta
+	r.stressWorkers[route] = active
+
+	r.stressActiveWorkers.WithLabelValues(route).Set(float64(active))
+	r.stressSaturation.WithLabelValues(route).Set(float64(active) / float64(runtime.NumCPU()))
+}
+
+// TrackStressRun implements Recorder.
+func (r *Registry) TrackStressRun(mode, outcome string) {
+	r.stressRunsTotal.WithLabelValues(mode, outcome).Inc()
+}
+
+// AddStressMemory implements Recorder.
+func (r *Registry) AddStressMemory(delta float64) {
+	r.stressMemoryBytes.Add(delta)
+}
+
+// TrackInjectedFault implements Recorder.
+func (r *Registry) TrackInjectedFault(faultType, code string) {
+	r.injectedFaultsTotal.WithLabelValues(faultType, code).Inc()
+}
+
+// TrackPanic implements Recorder.
+func (r *Registry) TrackPanic(route string) {
+	r.panicsTotal.WithLabelValues(route).Inc()
+}
+
+// SetConfigGeneration implements Recorder.
+func (r *Registry) SetConfigGeneration(generation uint64) {
+	r.configGeneration.Set(float64(generation))
+}
+
+// TrackConfigReload implements Recorder.
+func (r *Registry) TrackConfigReload(success bool) {
+	r.configLastReloadTimestamp.SetToCurrentTime()
+	if success {
+		r.configLastReloadSuccess.Set(1)
+	} else {
+		r.configLastReloadSuccess.Set(0)
+	}
 }
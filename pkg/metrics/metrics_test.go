@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counterValue returns the value of the counter family name with the given
+// label values gathered from g, or 0 if there is no such series.
+func counterValue(t *testing.T, g prometheus.Gatherer, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	series:
+		for _, m := range family.GetMetric() {
+			for _, pair := range m.GetLabel() {
+				if want, ok := labels[pair.GetName()]; ok && want != pair.GetValue() {
+					continue series
+				}
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestNewRegistryCustomRegistererIsolation(t *testing.T) {
+	newIsolated := func(t *testing.T) (*prometheus.Registry, *Registry) {
+		t.Helper()
+
+		promRegistry := prometheus.NewRegistry()
+		opts := DefaultOptions()
+		opts.Registerer = promRegistry
+		opts.GoCollector = false
+		opts.ProcessCollector = false
+
+		reg, err := NewRegistry(opts)
+		if err != nil {
+			t.Fatalf("NewRegistry() error = %v", err)
+		}
+		return promRegistry, reg
+	}
+
+	promA, regA := newIsolated(t)
+	promB, regB := newIsolated(t)
+
+	regA.TrackStressRun("cpu", "completed")
+	regA.TrackStressRun("cpu", "completed")
+	regB.TrackInjectedFault("error", "503")
+
+	runs := map[string]string{"mode": "cpu", "outcome": "completed"}
+	if got := counterValue(t, promA, "stress_runs_total", runs); got != 2 {
+		t.Errorf("registry A stress_runs_total = %v, want 2", got)
+	}
+	if got := counterValue(t, promB, "stress_runs_total", runs); got != 0 {
+		t.Errorf("registry B stress_runs_total = %v, want 0", got)
+	}
+	if got := counterValue(t, promA, "injected_faults_total", nil); got != 0 {
+		t.Errorf("registry A injected_faults_total = %v, want 0", got)
+	}
+
+	// Without an explicit Gatherer, the handler must serve the custom
+	// registry rather than prometheus.DefaultGatherer.
+	for name, tc := range map[string]struct {
+		reg        *Registry
+		want, skip string
+	}{
+		"A": {reg: regA, want: "stress_runs_total", skip: "injected_faults_total"},
+		"B": {reg: regB, want: "injected_faults_total", skip: `stress_runs_total{`},
+	} {
+		rec := httptest.NewRecorder()
+		tc.reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		body, _ := io.ReadAll(rec.Body)
+
+		if !strings.Contains(string(body), tc.want) {
+			t.Errorf("registry %s handler output lacks %s:\n%s", name, tc.want, body)
+		}
+		if strings.Contains(string(body), tc.skip) {
+			t.Errorf("registry %s handler output has %s from the other registry:\n%s", name, tc.skip, body)
+		}
+	}
+}
+
+func TestNewRegistryDefaultIsolation(t *testing.T) {
+	opts := DefaultOptions()
+	opts.GoCollector = false
+	opts.ProcessCollector = false
+
+	// Two registries without a Registerer each get their own
+	// prometheus.Registry, so registering the same collectors twice works.
+	if _, err := NewRegistry(opts); err != nil {
+		t.Fatalf("first NewRegistry() error = %v", err)
+	}
+	if _, err := NewRegistry(opts); err != nil {
+		t.Fatalf("second NewRegistry() error = %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package metrics
+
+// nop is a Recorder that discards all metrics.
+type nop struct{}
+
+// Nop returns a Recorder that discards all metrics, for tests and for code
+// that runs without a Registry. Its scaling signals are always zero.
+func Nop() Recorder {
+	return nop{}
+}
+
+func (nop) TrackRequest(string, string, int)                                       {}
+func (nop) ObserveRequestDuration(string, string, int, float64, map[string]string) {}
+func (nop) ObserveRequestSizes(string, string, int, int64, int64)                  {}
+func (nop) StartRequestInFlight() func()                                           { return func() {} }
+func (nop) StartScalingRequest() func()                                            { return func() {} }
+func (nop) AddStressQueueDepth(int64)                                              {}
+func (nop) ScalingSignals() ScalingSignals                                         { return ScalingSignals{} }
+func (nop) AddStressWorkers(string, int)                                           {}
+func (nop) TrackStressRun(string, string)                                          {}
+func (nop) AddStressMemory(float64)                                                {}
+func (nop) TrackInjectedFault(string, string)                                      {}
+func (nop) TrackPanic(string)                                                      {}
+func (nop) SetConfigGeneration(uint64)                                             {}
+func (nop) TrackConfigReload(bool)                                                 {}
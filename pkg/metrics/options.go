@@ -0,0 +1,85 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Options configures the application metrics. Use DefaultOptions as the
+// starting point and override individual fields.
+type Options struct {
+	// Registerer receives the collectors. When nil, a new
+	// prometheus.Registry is created and used as Gatherer too.
+	Registerer prometheus.Registerer
+
+	// Gatherer is served by Registry.Handler. It is ignored when Registerer
+	// is nil. Otherwise it defaults to Registerer if that is also a
+	// prometheus.Gatherer, such as a *prometheus.Registry, and to
+	// prometheus.DefaultGatherer if not.
+	Gatherer prometheus.Gatherer
+
+	// GoCollector registers the Go runtime collector (go_* metrics).
+	GoCollector bool
+
+	// ProcessCollector registers the process collector (process_* metrics).
+	ProcessCollector bool
+
+	// DurationBuckets are the request duration histogram buckets, in
+	// seconds, of routes that belong to no route class.
+	DurationBuckets []float64
+
+	// RouteClasses give groups of routes their own duration buckets, for
+	// routes whose latency differs by orders of magnitude from the rest.
+	// A route belongs to the first class that lists it.
+	RouteClasses []RouteClass
+
+	// NativeHistograms additionally exposes request durations as Prometheus
+	// native histograms, which have high resolution at any latency without
+	// configured buckets. The classic buckets are still exposed. Prometheus
+	// must run with --enable-feature=native-histograms to scrape them.
+	NativeHistograms bool
+
+	// NativeHistogramBucketFactor is the maximum ratio between the bounds
+	// of neighbouring native histogram buckets. Defaults to 1.1.
+	NativeHistogramBucketFactor float64
+
+	// Exemplars attaches the trace or request ID of an observed request to
+	// the request duration histogram, so a slow bucket links to an example
+	// request. Exemplars are only exposed in the OpenMetrics format.
+	Exemplars bool
+}
+
+// RouteClass assigns duration buckets to a group of routes.
+type RouteClass struct {
+	// Name identifies the class in logs and configuration.
+	Name string
+
+	// Routes are the route templates in the class, as used in the path label.
+	Routes []string
+
+	// Buckets are the duration histogram buckets of the class in seconds.
+	Buckets []float64
+}
+
+// StressDurationBuckets are the default duration buckets of the stress route
+// class. They cover CPU stress runs and injected fault delays of up to 30s
+// and memory stress runs of up to 5m, which would mostly land in the +Inf
+// bucket of prometheus.DefBuckets.
+var StressDurationBuckets = []float64{0.5, 1, 2.5, 5, 10, 15, 20, 30, 45, 60, 120, 300}
+
+// DefaultOptions returns the default metrics options: a new registry with the
+// Go runtime and process collectors, prometheus.DefBuckets for regular routes
+// and StressDurationBuckets for the long-running stress and fault routes,
+// with native histograms and exemplars disabled.
+func DefaultOptions() Options {
+	return Options{
+		GoCollector:      true,
+		ProcessCollector: true,
+		DurationBuckets:  prometheus.DefBuckets,
+		RouteClasses: []RouteClass{
+			{
+				Name:    "stress",
+				Routes:  []string{"/stress", "/stress/memory", "/fault"},
+				Buckets: StressDurationBuckets,
+			},
+		},
+		NativeHistogramBucketFactor: 1.1,
+	}
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Scaling signal names. They are both the Prometheus metric names and the
+// metric names served by the custom metrics API (internal/custommetrics).
+const (
+	ScalingInFlightRequests = "scaling_in_flight_requests"
+	ScalingStressQueueDepth = "scaling_stress_queue_depth"
+	ScalingRequestRate      = "scaling_request_rate"
+)
+
+// requestRateWindow is the sliding window over which the request rate is
+// averaged. It is long enough to smooth bursts and short enough to follow
+// load changes within one HPA sync period.
+const requestRateWindow = 30 * time.Second
+
+// ScalingSignals is a snapshot of the per-pod values meant to drive
+// horizontal scaling. Unlike CPU, they describe application load directly.
+type ScalingSignals struct {
+	// InFlightRequests is the number of application requests currently
+	// being served. Probe, metrics and admin requests are excluded.
+	InFlightRequests float64 `json:"scaling_in_flight_requests"`
+
+	// StressQueueDepth is the number of stress runs executing, counting
+	// both synchronous requests and background jobs.
+	StressQueueDepth float64 `json:"scaling_stress_queue_depth"`
+
+	// RequestRate is the application request rate in requests per second,
+	// averaged over the last 30 seconds.
+	RequestRate float64 `json:"scaling_request_rate"`
+}
+
+// Values returns the signals keyed by metric name.
+func (s ScalingSignals) Values() map[string]float64 {
+	return map[string]float64{
+		ScalingInFlightRequests: s.InFlightRequests,
+		ScalingStressQueueDepth: s.StressQueueDepth,
+		ScalingRequestRate:      s.RequestRate,
+	}
+}
+
+// ScalingSignalNames lists the names of all scaling signals.
+func ScalingSignalNames() []string {
+	return []string{ScalingInFlightRequests, ScalingStressQueueDepth, ScalingRequestRate}
+}
+
+// scalingState holds the current values of the scaling signals of a
+// Registry. They are kept outside Prometheus so they can be read directly by
+// the custom metrics API.
+type scalingState struct {
+	inFlight    atomic.Int64
+	stressQueue atomic.Int64
+	rate        *rateWindow
+}
+
+// newScalingState creates an empty scalingState.
+func newScalingState() *scalingState {
+	return &scalingState{rate: newRateWindow(requestRateWindow)}
+}
+
+// collectors returns the scaling signal gauges, which read the current
+// values on every scrape.
+func (s *scalingState) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: ScalingInFlightRequests,
+				Help: "Application requests currently in flight, excluding probes, metrics and admin requests",
+			},
+			func() float64 { return float64(s.inFlight.Load()) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: ScalingStressQueueDepth,
+				Help: "Stress runs currently executing, including background jobs",
+			},
+			func() float64 { return float64(s.stressQueue.Load()) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: ScalingRequestRate,
+				Help: "Application requests per second, averaged over the last 30 seconds",
+			},
+			func() float64 { return s.rate.rate(time.Now()) },
+		),
+	}
+}
+
+// startRequest records the start of an application request and returns the
+// function recording its completion.
+func (s *scalingState) startRequest() func() {
+	s.inFlight.Add(1)
+	s.rate.add(time.Now())
+
+	return func() {
+		s.inFlight.Add(-1)
+	}
+}
+
+// current returns the current scaling signal values.
+func (s *scalingState) current() ScalingSignals {
+	return ScalingSignals{
+		InFlightRequests: float64(s.inFlight.Load()),
+		StressQueueDepth: float64(s.stressQueue.Load()),
+		RequestRate:      s.rate.rate(time.Now()),
+	}
+}
+
+// rateWindow counts events in one-second buckets over a sliding window.
+type rateWindow struct {
+	mu      sync.Mutex
+	seconds []int64
+	counts  []float64
+}
+
+// newRateWindow creates a rateWindow covering window.
+func newRateWindow(window time.Duration) *rateWindow {
+	size := int(window / time.Second)
+	return &rateWindow{
+		seconds: make([]int64, size),
+		counts:  make([]float64, size),
+	}
+}
+
+// add records one event at now.
+func (w *rateWindow) add(now time.Time) {
+	second := now.Unix()
+	slot := int(second % int64(len(w.seconds)))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.seconds[slot] != second {
+		w.seconds[slot] = second
+		w.counts[slot] = 0
+	}
+	w.counts[slot]++
+}
+
+// rate returns the events per second over the window ending at now.
+func (w *rateWindow) rate(now time.Time) float64 {
+	oldest := now.Unix() - int64(len(w.seconds)) + 1
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var total float64
+	for i, second := range w.seconds {
+		if second >= oldest {
+			total += w.counts[i]
+		}
+	}
+	return total / float64(len(w.seconds))
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateWindow(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	w := newRateWindow(10 * time.Second)
+
+	if got := w.rate(start); got != 0 {
+		t.Fatalf("rate of an empty window = %v, want 0", got)
+	}
+
+	// 20 events in the first second, then 5 per second for 4 seconds
+	for range 20 {
+		w.add(start)
+	}
+	for s := 1; s <= 4; s++ {
+		for range 5 {
+			w.add(start.Add(time.Duration(s)*time.Second + 500*time.Millisecond))
+		}
+	}
+
+	tests := []struct {
+		name string
+		at   time.Duration
+		want float64
+	}{
+		{name: "all events in the window", at: 4 * time.Second, want: 4},
+		{name: "end of the window", at: 9 * time.Second, want: 4},
+		{name: "first second dropped", at: 10 * time.Second, want: 2},
+		{name: "partially expired", at: 12 * time.Second, want: 1},
+		{name: "all expired", at: 15 * time.Second, want: 0},
+		{name: "long idle", at: time.Hour, want: 0},
+	}
+	for _, tt := range tests {
+		if got := w.rate(start.Add(tt.at)); got != tt.want {
+			t.Errorf("%s: rate at +%v = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestRateWindowReusesSlots(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	w := newRateWindow(3 * time.Second)
+
+	for range 9 {
+		w.add(start)
+	}
+	// Three seconds later the same slot is reused, dropping the old count
+	w.add(start.Add(3 * time.Second))
+
+	if got := w.rate(start.Add(3 * time.Second)); got != 1.0/3 {
+		t.Errorf("rate after slot reuse = %v, want %v", got, 1.0/3)
+	}
+}
+
+func TestScalingSignals(t *testing.T) {
+	s := newScalingState()
+
+	done := []func(){s.startRequest(), s.startRequest(), s.startRequest()}
+	done[0]()
+	s.stressQueue.Add(2)
+
+	got := s.current()
+	if got.InFlightRequests != 2 || got.StressQueueDepth != 2 {
+		t.Errorf("current() = %+v, want 2 in flight and a queue depth of 2", got)
+	}
+	if want := 3 / requestRateWindow.Seconds(); got.RequestRate != want {
+		t.Errorf("RequestRate = %v, want %v", got.RequestRate, want)
+	}
+
+	values := got.Values()
+	for _, name := range ScalingSignalNames() {
+		if _, ok := values[name]; !ok {
+			t.Errorf("Values() lacks %s", name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package requestid carries request correlation IDs through contexts.
+//
+// A request ID identifies one HTTP request across the client, the logs and
+// the response. The request ID middleware accepts an ID from the X-Request-ID
+// header or generates one, and stores it in the request context with
+// NewContext. Log events given that context carry it as request_id.
+//
+// Example usage:
+//
+//	ctx := requestid.NewContext(r.Context(), requestid.New())
+//	id := requestid.FromContext(ctx)
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header is the HTTP header carrying the request ID in both directions.
+const Header = "X-Request-ID"
+
+// MaxLength is the longest request ID accepted from a client.
+const MaxLength = 128
+
+// contextKey is the context key of the request ID.
+type contextKey struct{}
+
+// New returns a new random request ID of 32 hex characters.
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether id is acceptable as a request ID from a client: 1 to
+// MaxLength printable ASCII characters without spaces. This keeps IDs safe to
+// echo in headers and to use as log and exemplar values.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx carrying the request ID id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
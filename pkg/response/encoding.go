@@ -0,0 +1,227 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v2"
+)
+
+// format is a response encoding that Send can negotiate.
+type format struct {
+	// contentType is sent in the Content-Type header.
+	contentType string
+
+	// mediaTypes are the Accept media types selecting the format.
+	mediaTypes []string
+
+	// encode encodes v. pretty asks for indented output, where supported.
+	encode func(v any, pretty bool) ([]byte, error)
+}
+
+// formats are the supported encodings in order of preference, used to
+// break ties between equally acceptable formats. JSON comes first: it is
+// the default when the client accepts none of them.
+var formats = []format{
+	{
+		contentType: "application/json",
+		mediaTypes:  []string{"application/json"},
+		encode:      encodeJSON,
+	},
+	{
+		contentType: "application/yaml",
+		mediaTypes:  []string{"application/yaml", "application/x-yaml", "text/yaml"},
+		encode:      encodeYAML,
+	},
+	{
+		contentType: "application/msgpack",
+		mediaTypes:  []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+		encode:      encodeMsgpack,
+	},
+	{
+		contentType: "text/plain; charset=utf-8",
+		mediaTypes:  []string{"text/plain"},
+		encode:      encodeText,
+	},
+}
+
+// negotiate returns the format the Accept header of r gives the highest
+// quality, or JSON if it accepts none of them.
+func negotiate(r *http.Request) format {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return formats[0]
+	}
+
+	best, bestQuality := formats[0], 0.0
+	for _, f := range formats {
+		for _, mediaType := range f.mediaTypes {
+			if quality, _ := acceptQuality(accept, mediaType); quality > bestQuality {
+				best, bestQuality = f, quality
+			}
+		}
+	}
+	return best
+}
+
+// wantsPretty reports whether r asks for indented output with the pretty
+// query parameter (e.g. /version?pretty).
+func wantsPretty(r *http.Request) bool {
+	return r.URL.Query().Has("pretty")
+}
+
+// encodeJSON encodes v as JSON followed by a newline, like json.Encoder.
+func encodeJSON(v any, pretty bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeYAML encodes v as YAML.
+func encodeYAML(v any, _ bool) ([]byte, error) {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// encodeMsgpack encodes v as MessagePack, with map keys sorted so that
+// equal values always encode to equal bytes.
+func encodeMsgpack(v any, _ bool) ([]byte, error) {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeText encodes v as "key: value" lines sorted by key, with nested
+// keys joined by dots (e.g. "errors.0.field: workers").
+func encodeText(v any, _ bool) ([]byte, error) {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeText(&buf, "", generic)
+	return buf.Bytes(), nil
+}
+
+// writeText writes v to buf as encodeText does, prefixing keys with prefix.
+func writeText(buf *bytes.Buffer, prefix string, v any) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			writeText(buf, join(key), v[key])
+		}
+	case []any:
+		for i, elem := range v {
+			writeText(buf, join(strconv.Itoa(i)), elem)
+		}
+	default:
+		if prefix != "" {
+			buf.WriteString(prefix + ": ")
+		}
+		buf.WriteString(textValue(v) + "\n")
+	}
+}
+
+// textValue formats a scalar produced by toGeneric.
+func textValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toGeneric converts v to the maps, slices and scalars of its JSON
+// representation, so that every format uses the same field names and
+// custom JSON encodings. Integers become int64 rather than float64, so they
+// keep their type in MessagePack.
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return convertNumbers(generic), nil
+}
+
+// convertNumbers replaces the json.Number values in v with int64 or
+// float64.
+func convertNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, elem := range v {
+			v[key] = convertNumbers(elem)
+		}
+	case []any:
+		for i, elem := range v {
+			v[i] = convertNumbers(elem)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// addVary adds value to the Vary header of h unless it is already listed.
+func addVary(h http.Header, value string) {
+	for _, existing := range h.Values("Vary") {
+		for _, field := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
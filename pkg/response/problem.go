@@ -0,0 +1,158 @@
+package response
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
+)
+
+// ProblemContentType is the media type of Problem responses, defined by
+// RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, sent by SendError instead of
+// the legacy error Response to clients that ask for ProblemContentType.
+type Problem struct {
+	// Type is a URI reference identifying the problem type. It is
+	// "about:blank" for problems described by their status code alone.
+	Type string `json:"type"`
+
+	// Title is a short summary of the problem type; for "about:blank" the
+	// status text, such as "Bad Request".
+	Title string `json:"title"`
+
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+
+	// Detail explains this occurrence of the problem. It carries the same
+	// text as Response.Message in the legacy format.
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is a URI reference identifying this occurrence of the
+	// problem, the path of the request.
+	Instance string `json:"instance,omitempty"`
+
+	// Errors is an extension member listing the invalid request fields,
+	// for validation failures.
+	Errors []FieldError `json:"errors,omitempty"`
+
+	// TraceID and RequestID are extension members identifying the request,
+	// as in Response.
+	TraceID   string `json:"trace_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldError describes why a single request field, such as a query
+// parameter, is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	// Value is the rejected value as given in the request, if any.
+	Value string `json:"value,omitempty"`
+
+	// Min and Max are the effective bounds of the field, if it has any.
+	Min any `json:"min,omitempty"`
+	Max any `json:"max,omitempty"`
+}
+
+// NewProblem creates an "about:blank" Problem for statusCode, titled with
+// its status text.
+func NewProblem(statusCode int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+	}
+}
+
+// SendError writes an error response with the given status code and
+// message, in the format r asks for (see WantsProblem): a Problem with
+// fieldErrors as its errors member, or by default the legacy error Response,
+// which omits fieldErrors for backward compatibility. The legacy Response is
+// sent with Send, so it is encoded in the format negotiated there.
+//
+// Like SendJSON, it fills in the trace and request IDs from the headers
+// already set on w.
+func SendError(w http.ResponseWriter, r *http.Request, statusCode int, message string, fieldErrors ...FieldError) {
+	addVary(w.Header(), "Accept")
+
+	if !WantsProblem(r) {
+		Send(w, r, statusCode, Error(message))
+		return
+	}
+
+	problem := NewProblem(statusCode, message)
+	problem.Instance = r.URL.Path
+	problem.Errors = fieldErrors
+	problem.TraceID = w.Header().Get(TraceIDHeader)
+	problem.RequestID = w.Header().Get(requestid.Header)
+
+	body, err := encodeJSON(problem, wantsPretty(r))
+	write(r.Context(), w, statusCode, ProblemContentType, body, err)
+}
+
+// WantsProblem reports whether the Accept header of r prefers
+// ProblemContentType over plain application/json. Because the legacy format
+// is the default, this requires the client to name ProblemContentType
+// explicitly: a request without an Accept header, or accepting only */* or
+// application/*, gets the legacy format.
+func WantsProblem(r *http.Request) bool {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return false
+	}
+
+	problemQuality, explicit := acceptQuality(accept, ProblemContentType)
+	jsonQuality, _ := acceptQuality(accept, "application/json")
+
+	if problemQuality == 0 {
+		return false
+	}
+	return problemQuality > jsonQuality || (problemQuality == jsonQuality && explicit)
+}
+
+// acceptQuality returns the quality the Accept header values give
+// mediaType, from the most specific media range matching it, and whether
+// that range names mediaType exactly. Unmatched media types have quality 0.
+func acceptQuality(accept []string, mediaType string) (quality float64, exact bool) {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	specificity := -1
+
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			rangeType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+
+			var s int
+			switch {
+			case rangeType == mediaType:
+				s = 2
+			case rangeType == typ+"/*":
+				s = 1
+			case rangeType == "*/*":
+				s = 0
+			default:
+				continue
+			}
+			if s <= specificity {
+				continue
+			}
+
+			q := 1.0
+			if qv, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(qv, 64); err == nil && parsed >= 0 && parsed <= 1 {
+					q = parsed
+				}
+			}
+			specificity, quality, exact = s, q, s == 2
+		}
+	}
+	return quality, exact
+}
@@ -1,20 +1,41 @@
 // Package response provides standardized HTTP response types and utilities.
 //
 // This package defines a consistent response structure for API endpoints,
-// ensuring all responses follow a predictable JSON format. It includes
-// helper functions for encoding and sending responses.
+// ensuring all responses follow a predictable format. It includes helper
+// functions for encoding and sending responses in the format the client
+// asks for: JSON by default, or YAML, MessagePack or plain text.
 //
 // Example usage:
 //
 //	resp := response.New("success", "Operation completed", "v1.0.0")
-//	response.SendJSON(w, http.StatusOK, resp)
+//	response.Send(w, r, http.StatusOK, resp)
+//
+// Errors are sent with SendError, which negotiates between the legacy error
+// Response and RFC 7807 problem details (see Problem):
+//
+//	response.SendError(w, r, http.StatusNotFound, "job not found")
 package response
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
+	"strings"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/logger"
+	"github.com/moabdelazem/go-gitops-app/pkg/requestid"
 )
 
+// StatusClientClosedRequest is the non-standard status, borrowed from nginx,
+// recorded for requests abandoned because the client disconnected. It lets
+// metrics and logs tell them apart from server errors.
+const StatusClientClosedRequest = 499
+
+// TraceIDHeader is the response header carrying the ID of the trace the
+// request belongs to. SendJSON copies it into Response.TraceID.
+const TraceIDHeader = "X-Trace-ID"
+
 // Response represents a standardized API response structure.
 // All API endpoints should return responses in this format to ensure
 // consistency across the application.
@@ -25,9 +46,19 @@ type Response struct {
 	// Message provides human-readable information about the response.
 	Message string `json:"message"`
 
-	// Version indicates the API version that generated this response.
-	// This field is optional and may be empty for certain responses.
+	// Version is the build version of the application that generated this
+	// response (see pkg/version). This field is optional and may be empty
+	// for certain responses.
 	Version string `json:"version,omitempty"`
+
+	// TraceID is the OpenTelemetry trace ID of the request, taken from the
+	// TraceIDHeader response header by SendJSON. It is empty outside a
+	// traced request.
+	TraceID string `json:"trace_id,omitempty"`
+
+	// RequestID is the correlation ID of the request, taken from the
+	// X-Request-ID response header by SendJSON.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // New creates a new Response with the specified status, message, and version.
@@ -58,6 +89,69 @@ func Success(message string) Response {
 	}
 }
 
+// Send encodes data in the format negotiated from the Accept header of r
+// and writes it with the given status code: JSON (the default, indented
+// with ?pretty), YAML, MessagePack or plain text "key: value" lines. All
+// formats use the field names of the JSON encoding.
+//
+// The body is encoded before anything is written, so if encoding fails the
+// client gets a real 500 Internal Server Error instead of statusCode.
+//
+// If data is a Response, a missing TraceID or RequestID is filled in from
+// the TraceIDHeader and X-Request-ID headers already set on w, if any.
+func Send(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	f := negotiate(r)
+	addVary(w.Header(), "Accept")
+
+	body, err := f.encode(withIDs(w, data), wantsPretty(r))
+	write(r.Context(), w, statusCode, f.contentType, body, err)
+}
+
+// SendCacheable sends data like Send with a 200 status, for responses that
+// only change when the application does, such as the version. It sets a weak
+// ETag computed from the encoded body, ignoring the per-request trace and
+// request IDs, and answers GET and HEAD requests whose If-None-Match header
+// lists that ETag with 304 Not Modified and no body.
+func SendCacheable(w http.ResponseWriter, r *http.Request, data interface{}) {
+	f := negotiate(r)
+	addVary(w.Header(), "Accept")
+
+	body, err := f.encode(data, wantsPretty(r))
+	if err != nil {
+		write(r.Context(), w, http.StatusOK, f.contentType, nil, err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	Send(w, r, http.StatusOK, data)
+}
+
+// etagMatches reports whether the If-None-Match header value ifNoneMatch
+// lists etag, using the weak comparison required for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // SendJSON encodes the provided data as JSON and writes it to the ResponseWriter.
 // It sets the appropriate Content-Type header and HTTP status code.
 //
@@ -66,13 +160,52 @@ func Success(message string) Response {
 //   - statusCode: The HTTP status code to set (e.g., http.StatusOK).
 //   - data: The data to encode as JSON. This can be any type that is JSON-serializable.
 //
-// If JSON encoding fails, a 500 Internal Server Error is returned.
+// If data is a Response, a missing TraceID or RequestID is filled in from
+// the TraceIDHeader and X-Request-ID headers already set on w, if any.
+//
+// If JSON encoding fails, a 500 Internal Server Error is returned. Prefer
+// Send, which also honors the Accept header.
 func SendJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	body, err := encodeJSON(withIDs(w, data), false)
+	write(context.Background(), w, statusCode, "application/json", body, err)
+}
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		// Log encoding error - this is a critical failure
-		http.Error(w, `{"status":"error","message":"Failed to encode response"}`, http.StatusInternalServerError)
+// withIDs returns data with a missing TraceID or RequestID filled in from
+// the headers set on w if it is a Response, and data unchanged otherwise.
+func withIDs(w http.ResponseWriter, data interface{}) interface{} {
+	resp, ok := data.(Response)
+	if !ok {
+		return data
 	}
+
+	if resp.TraceID == "" {
+		resp.TraceID = w.Header().Get(TraceIDHeader)
+	}
+	if resp.RequestID == "" {
+		resp.RequestID = w.Header().Get(requestid.Header)
+	}
+	return resp
+}
+
+// encodeErrorBody is sent with a 500 status when a response fails to encode.
+const encodeErrorBody = `{"status":"error","message":"Failed to encode response"}` + "\n"
+
+// write writes body to w with the given status code and Content-Type. If
+// encodeErr is set, body could not be encoded; the error is logged with the
+// logger of ctx and a 500 JSON error is written instead.
+func write(ctx context.Context, w http.ResponseWriter, statusCode int, contentType string, body []byte, encodeErr error) {
+	if encodeErr != nil {
+		logger.FromContext(ctx).Error().
+			Err(encodeErr).
+			Str("content_type", contentType).
+			Msg("Failed to encode response")
+
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		statusCode, contentType, body = http.StatusInternalServerError, "application/json", []byte(encodeErrorBody)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
 }
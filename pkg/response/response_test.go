@@ -0,0 +1,205 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v2"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept []string
+		want   string
+	}{
+		{name: "no Accept header", want: "application/json"},
+		{name: "any type", accept: []string{"*/*"}, want: "application/json"},
+		{name: "unsupported type", accept: []string{"image/png"}, want: "application/json"},
+		{name: "yaml", accept: []string{"application/yaml"}, want: "application/yaml"},
+		{name: "yaml alias", accept: []string{"text/yaml"}, want: "application/yaml"},
+		{name: "msgpack alias", accept: []string{"application/x-msgpack"}, want: "application/msgpack"},
+		{name: "text", accept: []string{"text/plain"}, want: "text/plain; charset=utf-8"},
+		{name: "text wildcard", accept: []string{"text/*"}, want: "application/yaml"},
+		{
+			name:   "highest quality wins",
+			accept: []string{"application/json;q=0.5, application/yaml;q=0.9"},
+			want:   "application/yaml",
+		},
+		{
+			name:   "tie keeps preference order",
+			accept: []string{"application/yaml, application/json"},
+			want:   "application/json",
+		},
+		{
+			name:   "specific range overrides wildcard",
+			accept: []string{"*/*;q=0.1, application/msgpack"},
+			want:   "application/msgpack",
+		},
+		{
+			name:   "q=0 refuses a type",
+			accept: []string{"application/json;q=0, text/plain;q=0.2"},
+			want:   "text/plain; charset=utf-8",
+		},
+		{
+			name:   "invalid q is ignored",
+			accept: []string{"application/yaml;q=2"},
+			want:   "application/yaml",
+		},
+		{
+			name:   "multiple header values",
+			accept: []string{"application/json;q=0.1", "application/msgpack;q=0.8"},
+			want:   "application/msgpack",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, value := range tt.accept {
+				r.Header.Add("Accept", value)
+			}
+
+			if got := negotiate(r).contentType; got != tt.want {
+				t.Errorf("negotiate(%q) = %s, want %s", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWantsProblem(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/*", want: false},
+		{accept: "application/json", want: false},
+		{accept: "application/problem+json", want: true},
+		{accept: "application/json, application/problem+json", want: true},
+		{accept: "application/json, application/problem+json;q=0.5", want: false},
+		{accept: "application/problem+json;q=0", want: false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := WantsProblem(r); got != tt.want {
+			t.Errorf("WantsProblem(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestSendEncodings(t *testing.T) {
+	data := Response{Status: "success", Message: "hello"}
+
+	tests := []struct {
+		accept string
+		decode func([]byte) (map[string]any, error)
+	}{
+		{accept: "application/yaml", decode: func(b []byte) (map[string]any, error) {
+			var m map[string]any
+			return m, yaml.Unmarshal(b, &m)
+		}},
+		{accept: "application/msgpack", decode: func(b []byte) (map[string]any, error) {
+			var m map[string]any
+			return m, msgpack.Unmarshal(b, &m)
+		}},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", tt.accept)
+
+		Send(rec, r, http.StatusCreated, data)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("%s: status = %d, want %d", tt.accept, rec.Code, http.StatusCreated)
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.accept {
+			t.Errorf("%s: Content-Type = %s", tt.accept, got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept" {
+			t.Errorf("%s: Vary = %q, want Accept", tt.accept, got)
+		}
+		decoded, err := tt.decode(rec.Body.Bytes())
+		if err != nil {
+			t.Fatalf("%s: decode body: %v", tt.accept, err)
+		}
+		if decoded["status"] != "success" || decoded["message"] != "hello" {
+			t.Errorf("%s: body = %v, want the JSON field names and values", tt.accept, decoded)
+		}
+	}
+}
+
+func TestSendCacheable(t *testing.T) {
+	data := map[string]string{"version": "1.2.3"}
+
+	get := func(method, ifNoneMatch, accept string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/version", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		SendCacheable(rec, r, data)
+		return rec
+	}
+
+	first := get(http.MethodGet, "", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("status %d with ETag %q, want 200 with a weak ETag", first.Code, etag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", got)
+	}
+
+	// Trace and request IDs must not change the ETag
+	rec := httptest.NewRecorder()
+	rec.Header().Set(TraceIDHeader, "0123")
+	SendCacheable(rec, httptest.NewRequest(http.MethodGet, "/version", nil), data)
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Errorf("ETag with a trace ID = %q, want %q", got, etag)
+	}
+
+	if yamlETag := get(http.MethodGet, "", "application/yaml").Header().Get("ETag"); yamlETag == etag {
+		t.Error("YAML and JSON encodings share an ETag")
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "matching", method: http.MethodGet, ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "strong form", method: http.MethodGet, ifNoneMatch: strings.TrimPrefix(etag, "W/"), want: http.StatusNotModified},
+		{name: "in a list", method: http.MethodGet, ifNoneMatch: `"other", ` + etag, want: http.StatusNotModified},
+		{name: "wildcard", method: http.MethodGet, ifNoneMatch: "*", want: http.StatusNotModified},
+		{name: "HEAD", method: http.MethodHead, ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "stale", method: http.MethodGet, ifNoneMatch: `W/"stale"`, want: http.StatusOK},
+		{name: "POST ignores the precondition", method: http.MethodPost, ifNoneMatch: etag, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.method, tt.ifNoneMatch, "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 response has a body: %q", rec.Body)
+			}
+		})
+	}
+}
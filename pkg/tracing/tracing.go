@@ -0,0 +1,151 @@
+// Package tracing configures OpenTelemetry distributed tracing.
+//
+// Setup installs a global tracer provider that exports spans over OTLP
+// (HTTP or gRPC) or to stdout, and the W3C Trace Context and Baggage
+// propagators. Incoming traceparent headers are honored even when no
+// exporter is configured, so trace IDs still flow into logs and responses.
+//
+// Example usage:
+//
+//	shutdown, err := tracing.Setup(ctx, tracing.Options{Exporter: tracing.ExporterStdout, SampleRatio: 1})
+//	if err != nil {
+//		return err
+//	}
+//	defer shutdown(context.Background())
+//
+//	ctx, span := tracing.Tracer().Start(ctx, "work")
+//	defer span.End()
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/moabdelazem/go-gitops-app/pkg/version"
+)
+
+// Supported values for Options.Exporter.
+const (
+	// ExporterNone records no spans but still propagates trace context.
+	ExporterNone = "none"
+
+	// ExporterOTLPHTTP exports spans to an OTLP collector over HTTP.
+	ExporterOTLPHTTP = "otlp-http"
+
+	// ExporterOTLPGRPC exports spans to an OTLP collector over gRPC.
+	ExporterOTLPGRPC = "otlp-grpc"
+
+	// ExporterStdout writes spans to stdout as JSON, for local runs.
+	ExporterStdout = "stdout"
+)
+
+// instrumentationName identifies the application's tracer.
+const instrumentationName = "github.com/moabdelazem/go-gitops-app"
+
+// Options configures the tracer provider installed by Setup.
+type Options struct {
+	// Exporter is one of the Exporter constants. Empty means ExporterNone.
+	Exporter string
+
+	// Endpoint is the OTLP collector address, such as otel-collector:4318.
+	// Empty uses OTEL_EXPORTER_OTLP_ENDPOINT or the exporter default.
+	Endpoint string
+
+	// Insecure disables TLS towards the OTLP collector.
+	Insecure bool
+
+	// SampleRatio is the fraction of new traces that are recorded. Spans
+	// with a sampled parent are always recorded.
+	SampleRatio float64
+
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+}
+
+// Setup installs the global propagators and, unless opts.Exporter is
+// ExporterNone, a global tracer provider exporting spans in batches. The
+// returned function flushes pending spans and shuts the provider down; it
+// must be called before the process exits.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if opts.Exporter == "" || opts.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create %s exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName(opts.ServiceName),
+			semconv.ServiceVersion(version.Get().Version),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: build resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the span exporter selected by opts.Exporter.
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, error) {
+	switch opts.Exporter {
+	case ExporterOTLPHTTP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, clientOpts...)
+
+	case ExporterOTLPGRPC:
+		var clientOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, clientOpts...)
+
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", opts.Exporter)
+	}
+}
+
+// Tracer returns the application's tracer from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,121 @@
+// Package version reports build metadata for the running binary.
+//
+// The values are injected at build time with -ldflags, for example:
+//
+//	go build -ldflags "\
+//	  -X github.com/moabdelazem/go-gitops-app/pkg/version.Version=v1.2.0 \
+//	  -X github.com/moabdelazem/go-gitops-app/pkg/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/moabdelazem/go-gitops-app/pkg/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ) \
+//	  -X github.com/moabdelazem/go-gitops-app/pkg/version.Dirty=false" ./cmd
+//
+// The Makefile and Dockerfile do this automatically. Values that are not
+// injected fall back to the VCS information the Go toolchain embeds with
+// runtime/debug.ReadBuildInfo, which is available when building from a git
+// checkout with plain "go build".
+//
+// Example usage:
+//
+//	info := version.Get()
+//	logger.Info().Str("version", info.Version).Str("commit", info.Commit).Msg("Starting")
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// Build metadata injected with -ldflags "-X". They are strings because -X
+// can only set string variables.
+var (
+	// Version is the release version, such as "v1.2.0" or the output of
+	// "git describe --tags --always --dirty".
+	Version = ""
+
+	// Commit is the full git commit SHA the binary was built from.
+	Commit = ""
+
+	// BuildDate is the build time in RFC 3339 format.
+	BuildDate = ""
+
+	// Dirty is "true" if the working tree had uncommitted changes.
+	Dirty = ""
+)
+
+// unknown is reported for metadata that is neither injected nor embedded.
+const unknown = "unknown"
+
+// Info is the build metadata of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Dirty     bool   `json:"dirty"`
+}
+
+// ShortCommit returns the first 7 characters of the commit SHA, matching
+// the image tags produced by scripts/build-push.sh.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 7 && i.Commit != unknown {
+		return i.Commit[:7]
+	}
+	return i.Commit
+}
+
+// Get returns the build metadata. It is computed once and cached.
+var Get = sync.OnceValue(func() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	info.Dirty, _ = strconv.ParseBool(Dirty)
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		applyBuildInfo(&info, build)
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = unknown
+	}
+	return info
+})
+
+// applyBuildInfo fills metadata that was not injected at build time from the
+// information embedded by the Go toolchain.
+func applyBuildInfo(info *Info, build *debug.BuildInfo) {
+	if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+		info.Version = build.Main.Version
+	}
+
+	// Dirty is only taken from the toolchain together with the commit, so
+	// an injected commit is never paired with an unrelated dirty flag
+	injectedCommit := info.Commit != ""
+
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if !injectedCommit {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			// The commit time is the closest embedded approximation
+			if info.BuildDate == "" {
+				info.BuildDate = setting.Value
+			}
+		case "vcs.modified":
+			if !injectedCommit {
+				info.Dirty = setting.Value == "true"
+			}
+		}
+	}
+}
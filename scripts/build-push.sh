@@ -10,10 +10,19 @@ if [[ -z "$DOCKER_USERNAME" || -z "$IMAGE_NAME" ]]; then
 fi
 
 GIT_SHA=$(git rev-parse --short HEAD)
+COMMIT=$(git rev-parse HEAD)
+VERSION=$(git describe --tags --always --dirty)
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+if [[ -n "$(git status --porcelain)" ]]; then DIRTY=true; else DIRTY=false; fi
 IMAGE_BASE="${DOCKER_USERNAME}/${IMAGE_NAME}"
 
-echo "Building ${IMAGE_BASE}:${GIT_SHA}..."
-docker build -t "${IMAGE_BASE}:${GIT_SHA}" -t "${IMAGE_BASE}:latest" .
+echo "Building ${IMAGE_BASE}:${GIT_SHA} (version ${VERSION}, dirty ${DIRTY})..."
+docker build \
+    --build-arg VERSION="${VERSION}" \
+    --build-arg COMMIT="${COMMIT}" \
+    --build-arg BUILD_DATE="${BUILD_DATE}" \
+    --build-arg DIRTY="${DIRTY}" \
+    -t "${IMAGE_BASE}:${GIT_SHA}" -t "${IMAGE_BASE}:latest" .
 
 echo "Pushing images..."
 docker push "${IMAGE_BASE}:${GIT_SHA}"
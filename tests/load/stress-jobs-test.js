@@ -0,0 +1,48 @@
+import http from 'k6/http';
+import { check, sleep } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:8080';
+const STRESS_DURATION = __ENV.STRESS_DURATION || '10s';
+const WORKERS = __ENV.WORKERS || '2';
+
+export const options = {
+    stages: [
+        { duration: '30s', target: 5 },
+        { duration: '2m', target: 10 },
+        { duration: '1m', target: 15 },
+        { duration: '30s', target: 0 },
+    ],
+    thresholds: {
+        http_req_failed: ['rate<0.1'],
+    },
+};
+
+export function setup() {
+    const healthRes = http.get(`${BASE_URL}/health`);
+    check(healthRes, {
+        'health check passed': (r) => r.status === 200,
+    });
+
+    console.log(`Target: ${BASE_URL}`);
+    console.log(`Stress duration per job: ${STRESS_DURATION}`);
+    console.log(`CPU workers per job: ${WORKERS}`);
+
+    return { baseUrl: BASE_URL };
+}
+
+export default function (data) {
+    const url = `${data.baseUrl}/stress/jobs?duration=${STRESS_DURATION}&workers=${WORKERS}`;
+
+    // Jobs return immediately, so each iteration only holds a connection
+    // for the duration of the POST itself
+    const res = http.post(url, null, {
+        // 429 means the pod is already running its maximum number of jobs
+        responseCallback: http.expectedStatuses(202, 429),
+    });
+
+    check(res, {
+        'job accepted or throttled': (r) => r.status === 202 || r.status === 429,
+    });
+
+    sleep(5);
+}